@@ -0,0 +1,163 @@
+// This file contains an http.Handler you can mount into your admin endpoints to inspect and change the Loggers in runtime
+//
+//	GET          - lists all the registered Loggers with their level and handlers
+//	PUT or POST  - changes the level of a Logger, the request body looks like {"logger": "controller", "level": "debug", "ttl": "10m"}
+//	               where "ttl" is optional - if given then the previous level is restored after this time
+
+package kt_logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// one entry in the response of the admin handler
+type AdminLoggerModel struct {
+	Name          string   `json:"name"`
+	Level         string   `json:"level"`
+	InheritsLevel bool     `json:"inheritsLevel"`
+	Handlers      []string `json:"handlers"`
+}
+
+// the request body the admin handler accepts to change a Logger
+type AdminLevelChangeModel struct {
+	Logger string `json:"logger"`
+	Level  string `json:"level"`
+	// optional - Go duration string (e.g. "90s", "10m") after which the previous level is restored
+	TTL string `json:"ttl"`
+}
+
+// a level change which will be undone later
+type pendingLevelRestore struct {
+	timer         *time.Timer
+	level         LogLevel
+	inheritsLevel bool
+}
+
+// level restores scheduled by TTL - per logger name
+var pendingLevelRestores = map[string]*pendingLevelRestore{}
+var pendingLevelRestoresLock = new(sync.Mutex)
+
+// Returns an http.Handler which lists the registered Loggers (GET) and can change the level of a Logger (PUT / POST) - see the
+// doc on top of this file for details
+func AdminHandler() http.Handler {
+	return http.HandlerFunc(serveAdmin)
+}
+
+func serveAdmin(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeAdminJson(w, http.StatusOK, listLoggersForAdmin())
+	case http.MethodPut, http.MethodPost:
+		var change AdminLevelChangeModel
+		if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		loggerModel, err := applyAdminLevelChange(change)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeAdminJson(w, http.StatusOK, loggerModel)
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func applyAdminLevelChange(change AdminLevelChangeModel) (AdminLoggerModel, error) {
+	if change.Logger == "" {
+		return AdminLoggerModel{}, fmt.Errorf("'logger' is mandatory")
+	}
+	level, err := parseLogLevelString(change.Level)
+	if err != nil {
+		return AdminLoggerModel{}, err
+	}
+	var ttl time.Duration
+	if change.TTL != "" {
+		ttl, err = time.ParseDuration(change.TTL)
+		if err != nil || ttl <= 0 {
+			return AdminLoggerModel{}, fmt.Errorf("invalid ttl '%v'", change.TTL)
+		}
+	}
+
+	logger := GetLogger(change.Logger)
+
+	pendingLevelRestoresLock.Lock()
+	defer pendingLevelRestoresLock.Unlock()
+	previous, contains := pendingLevelRestores[change.Logger]
+	if contains {
+		// a former temporary change is overridden - if this one is temporary too we still restore the original level at the end
+		previous.timer.Stop()
+		delete(pendingLevelRestores, change.Logger)
+	}
+	if ttl > 0 {
+		// always a new instance - the timer of the former change might have fired already and be waiting for the lock, it must
+		// not take this one for its own
+		var pending *pendingLevelRestore
+		if contains {
+			pending = &pendingLevelRestore{level: previous.level, inheritsLevel: previous.inheritsLevel}
+		} else {
+			loggersLock.RLock()
+			pending = &pendingLevelRestore{level: logger.GetLevel(), inheritsLevel: logger.inheritsLevel}
+			loggersLock.RUnlock()
+		}
+		pending.timer = time.AfterFunc(ttl, func() {
+			pendingLevelRestoresLock.Lock()
+			defer pendingLevelRestoresLock.Unlock()
+			if pendingLevelRestores[change.Logger] == pending {
+				delete(pendingLevelRestores, change.Logger)
+				logger.restoreLevel(pending.level, pending.inheritsLevel)
+			}
+		})
+		pendingLevelRestores[change.Logger] = pending
+	}
+	logger.SetLevel(level)
+
+	loggersLock.RLock()
+	defer loggersLock.RUnlock()
+	return toAdminLoggerModel(logger), nil
+}
+
+func listLoggersForAdmin() []AdminLoggerModel {
+	// this makes sure the loggers are initialised
+	GetLogger(_ROOT_NAME)
+
+	loggersLock.RLock()
+	result := make([]AdminLoggerModel, 0, len(loggers))
+	for _, logger := range loggers {
+		result = append(result, toAdminLoggerModel(logger))
+	}
+	loggersLock.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// NOT THREAD SAFE! Already assumes (read) Lock is established.
+func toAdminLoggerModel(logger *Logger) AdminLoggerModel {
+	handlerNames := []string{}
+	for handlerName := range logger.GetOutputHandlers() {
+		handlerNames = append(handlerNames, handlerName)
+	}
+	sort.Strings(handlerNames)
+	return AdminLoggerModel{
+		Name:          logger.GetName(),
+		Level:         logger.GetLevel().String(),
+		InheritsLevel: logger.inheritsLevel,
+		Handlers:      handlerNames,
+	}
+}
+
+func writeAdminJson(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,144 @@
+// This file contains the 'async' option of the handlers - any Handler can be wrapped so encoding and writing the log events
+// happens in background goroutines instead of the goroutine which logs
+//
+// Log events are put into a bounded queue and the worker goroutines pass them to the wrapped Handler. If the queue is full the
+// configured overflow policy applies - dropped events are counted (see GetDroppedEventCount()) and reported on stderr. Sync()
+// and Close() wait until the queued events are written.
+
+package kt_logging
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	_DEFAULT_ASYNC_QUEUE_SIZE = 1000
+	_DEFAULT_ASYNC_WORKERS    = 1
+	// dropped events are reported on stderr at most this often - so we do not flood stderr while we are flooded
+	_ASYNC_DROP_REPORT_INTERVAL = time.Second
+)
+
+// implemented by Handlers wrapping another Handler (e.g. async) - so we can reach the capabilities of the wrapped one
+type handlerWrapper interface {
+	unwrap() Handler
+}
+
+// returns the innermost Handler if the given one is a wrapper
+func unwrapHandler(handler Handler) Handler {
+	for {
+		wrapper, isWrapper := handler.(handlerWrapper)
+		if !isWrapper {
+			return handler
+		}
+		handler = wrapper.unwrap()
+	}
+}
+
+type asyncHandler struct {
+	name    string
+	handler Handler
+	queue   *boundedQueue[LogRecord]
+	workers *sync.WaitGroup
+	// unix nanos of the last time we reported dropped events
+	lastDropReport atomic.Int64
+	closeOnce      sync.Once
+}
+
+// wraps the Handler so it is invoked from background goroutines
+func newAsyncHandler(handlerName string, handler Handler, model AsyncModel) (*asyncHandler, error) {
+	overflow, err := parseOverflowPolicy(model.Overflow)
+	if err != nil {
+		return nil, err
+	}
+	queueSize := withDefault(model.QueueSize, _DEFAULT_ASYNC_QUEUE_SIZE)
+	workers := withDefault(model.Workers, _DEFAULT_ASYNC_WORKERS)
+
+	h := &asyncHandler{
+		name:    handlerName,
+		handler: handler,
+		queue:   newBoundedQueue[LogRecord](queueSize, overflow),
+		workers: new(sync.WaitGroup),
+	}
+	h.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go h.work()
+	}
+	return h, nil
+}
+
+func (h *asyncHandler) Handle(record LogRecord) error {
+	if !h.queue.push(record) {
+		return errors.New("handler is closed")
+	}
+	return nil
+}
+
+// waits until the queued events are passed to the wrapped Handler then syncs it
+func (h *asyncHandler) Sync() error {
+	h.queue.waitIdle(func() bool { return false })
+	return h.handler.Sync()
+}
+
+// writes the queued events then closes the wrapped Handler
+func (h *asyncHandler) Close() error {
+	h.closeOnce.Do(h.queue.close)
+	h.workers.Wait()
+	h.reportDropped(true)
+	return h.handler.Close()
+}
+
+func (h *asyncHandler) unwrap() Handler {
+	return h.handler
+}
+
+func (h *asyncHandler) work() {
+	defer h.workers.Done()
+	for {
+		record, ok := h.queue.pop()
+		if !ok {
+			return
+		}
+		if err := h.handler.Handle(record); err != nil {
+			reportHandlerError(h.name, err)
+		}
+		h.queue.done()
+		h.reportDropped(false)
+	}
+}
+
+// writes the number of dropped events on stderr - unless we did it recently (or force is TRUE)
+func (h *asyncHandler) reportDropped(force bool) {
+	lastReport := h.lastDropReport.Load()
+	now := time.Now().UnixNano()
+	if !force && now-lastReport < int64(_ASYNC_DROP_REPORT_INTERVAL) {
+		return
+	}
+	if !h.lastDropReport.CompareAndSwap(lastReport, now) {
+		// another worker is reporting
+		return
+	}
+	if dropped := h.queue.takeDropped(); dropped > 0 {
+		fmt.Fprintf(os.Stderr, "kt_logging: %d log events were dropped as the async queue of handler '%v' was full\n", dropped, h.name)
+	}
+}
+
+// Returns the number of log events the handler with the given name dropped since it was created because its async queue was
+// full - 0 if the handler does not exist or it is not async
+func GetDroppedEventCount(handlerName string) uint64 {
+	loggersLock.RLock()
+	configured, found := activeHandlers.handlers[handlerName]
+	loggersLock.RUnlock()
+	if !found {
+		return 0
+	}
+	async, isAsync := configured.handler.(*asyncHandler)
+	if !isAsync {
+		return 0
+	}
+	return async.queue.getDroppedTotal()
+}
@@ -0,0 +1,215 @@
+// This file contains the batching logic shared by the handler types shipping log events in batches (e.g. "http")
+//
+// Log events are collected into a batch. The batch is handed over to a background goroutine once it is full (by count or by
+// size) or the flush interval elapsed. The background goroutine sends the batches one by one - retrying with exponential
+// backoff if sending fails with a retryable error. Batches still failing after the max retries are dropped.
+
+package kt_logging
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	_DEFAULT_BATCH_SIZE          = 100
+	_DEFAULT_BATCH_BYTES         = 1024 * 1024
+	_DEFAULT_FLUSH_INTERVAL      = time.Second
+	_DEFAULT_MAX_RETRIES         = 3
+	_DEFAULT_MAX_PENDING_BATCHES = 10
+	_BATCH_MIN_RETRY_DELAY       = 500 * time.Millisecond
+	_BATCH_MAX_RETRY_DELAY       = 30 * time.Second
+)
+
+// sending a batch failed with an error which does not go away with retrying (e.g. HTTP 400) - the batch is dropped right away
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+type batcher[T any] struct {
+	// describes the destination in error messages e.g. "http endpoint http://localhost:8080"
+	destination   string
+	maxItems      int
+	maxBytes      int
+	flushInterval time.Duration
+	maxRetries    int
+	// sends one batch
+	send func(batch []T) error
+
+	lock *sync.Mutex
+	// the batch being collected
+	items []T
+	bytes int
+
+	// the batches waiting to be sent
+	queue *boundedQueue[[]T]
+	// TRUE while the last attempt to send failed
+	failing atomic.Bool
+	// closed when the sender goroutine finished
+	senderDone chan struct{}
+	// closed when close() is invoked - stops the ticker and interrupts the waiting between retries
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+func newBatcher[T any](destination string, model BatchingModel, send func(batch []T) error) (*batcher[T], error) {
+	overflow, err := parseOverflowPolicy(model.Overflow)
+	if err != nil {
+		return nil, err
+	}
+	b := &batcher[T]{
+		destination:   destination,
+		maxItems:      withDefault(model.BatchSize, _DEFAULT_BATCH_SIZE),
+		maxBytes:      withDefault(model.BatchBytes, _DEFAULT_BATCH_BYTES),
+		flushInterval: time.Duration(model.FlushIntervalMs) * time.Millisecond,
+		maxRetries:    model.MaxRetries,
+		send:          send,
+		lock:          new(sync.Mutex),
+		queue:         newBoundedQueue[[]T](withDefault(model.MaxPendingBatches, _DEFAULT_MAX_PENDING_BATCHES), overflow),
+		senderDone:    make(chan struct{}),
+		closing:       make(chan struct{}),
+	}
+	if b.flushInterval <= 0 {
+		b.flushInterval = _DEFAULT_FLUSH_INTERVAL
+	}
+	switch {
+	case b.maxRetries == 0:
+		b.maxRetries = _DEFAULT_MAX_RETRIES
+	case b.maxRetries < 0:
+		// retries are disabled
+		b.maxRetries = 0
+	}
+	go b.sendLoop()
+	go b.flushLoop()
+	return b, nil
+}
+
+// returns the defaultValue if the configured value is not set (zero or negative)
+func withDefault(value int, defaultValue int) int {
+	if value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// adds the item to the current batch - size is the (approximate) number of bytes the item takes in the batch
+func (b *batcher[T]) add(item T, size int) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.queue.isClosed() {
+		return fmt.Errorf("handler is closed - can not send to %v anymore", b.destination)
+	}
+	if len(b.items) > 0 && b.bytes+size > b.maxBytes {
+		b.flushLocked()
+	}
+	b.items = append(b.items, item)
+	b.bytes += size
+	if len(b.items) >= b.maxItems || b.bytes >= b.maxBytes {
+		b.flushLocked()
+	}
+	return nil
+}
+
+// hands over the current batch to the sender
+func (b *batcher[T]) flush() {
+	b.lock.Lock()
+	b.flushLocked()
+	b.lock.Unlock()
+}
+
+// NOT THREAD SAFE! Already assumes Lock is established
+func (b *batcher[T]) flushLocked() {
+	if len(b.items) == 0 {
+		return
+	}
+	b.queue.push(b.items)
+	b.items = nil
+	b.bytes = 0
+}
+
+// sends the current batch and waits until all batches are sent - returns error if the destination is failing at the moment
+func (b *batcher[T]) sync() error {
+	b.flush()
+	pending := b.queue.waitIdle(b.failing.Load)
+	if pending > 0 {
+		return fmt.Errorf("sending to %v is failing - %d batches are waiting", b.destination, pending)
+	}
+	return nil
+}
+
+// sends the current batch then stops the background goroutines - batches are not retried anymore once we are closing
+func (b *batcher[T]) close() error {
+	b.closeOnce.Do(func() {
+		b.flush()
+		b.queue.close()
+		close(b.closing)
+	})
+	<-b.senderDone
+	return nil
+}
+
+func (b *batcher[T]) flushLoop() {
+	ticker := time.NewTicker(b.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.flush()
+		case <-b.closing:
+			return
+		}
+	}
+}
+
+func (b *batcher[T]) sendLoop() {
+	defer close(b.senderDone)
+	for {
+		batch, ok := b.queue.pop()
+		if !ok {
+			return
+		}
+		if err := b.sendWithRetry(batch); err != nil {
+			fmt.Fprintf(os.Stderr, "kt_logging: dropped a batch of %d log events - sending to %v failed: %v\n", len(batch), b.destination, err)
+		}
+		b.queue.done()
+		if dropped := b.queue.takeDropped(); dropped > 0 {
+			fmt.Fprintf(os.Stderr, "kt_logging: %d batches of log events were dropped as too many batches were waiting for %v\n", dropped, b.destination)
+		}
+	}
+}
+
+func (b *batcher[T]) sendWithRetry(batch []T) error {
+	retryDelay := _BATCH_MIN_RETRY_DELAY
+	for attempt := 0; ; attempt++ {
+		err := b.send(batch)
+		if err == nil {
+			b.failing.Store(false)
+			return nil
+		}
+		b.failing.Store(true)
+		b.queue.notify()
+		if attempt >= b.maxRetries || errors.As(err, &permanentError{}) {
+			return err
+		}
+		select {
+		case <-time.After(retryDelay):
+		case <-b.closing:
+			// we are shutting down - no point to wait
+			return err
+		}
+		retryDelay = min(retryDelay*2, _BATCH_MAX_RETRY_DELAY)
+	}
+}
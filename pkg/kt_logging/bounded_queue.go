@@ -0,0 +1,151 @@
+package kt_logging
+
+import (
+	"fmt"
+	"sync"
+)
+
+// what to do if a bounded queue is full and a new item arrives
+const (
+	_OVERFLOW_DROP_OLDEST string = "dropOldest"
+	_OVERFLOW_DROP_NEWEST string = "dropNewest"
+	_OVERFLOW_BLOCK       string = "block"
+)
+
+// validates the overflow policy coming from the config - empty string means the default dropOldest
+func parseOverflowPolicy(overflow string) (string, error) {
+	switch overflow {
+	case "":
+		return _OVERFLOW_DROP_OLDEST, nil
+	case _OVERFLOW_DROP_OLDEST, _OVERFLOW_DROP_NEWEST, _OVERFLOW_BLOCK:
+		return overflow, nil
+	default:
+		return "", fmt.Errorf("unknown overflow policy '%v' - 'dropOldest', 'dropNewest' or 'block' is supported", overflow)
+	}
+}
+
+// a FIFO queue with limited capacity - producers push, consumer goroutine(s) pop and mark the items done once processed
+type boundedQueue[T any] struct {
+	lock *sync.Mutex
+	// signals any change - items pushed / popped / done, closing
+	cond     *sync.Cond
+	items    []T
+	capacity int
+	overflow string
+	// number of items popped but not marked done yet
+	inProgress int
+	closed     bool
+	// number of items dropped due to overflow since the last takeDropped()
+	dropped uint64
+	// number of items dropped due to overflow since the queue was created
+	droppedTotal uint64
+}
+
+func newBoundedQueue[T any](capacity int, overflow string) *boundedQueue[T] {
+	lock := new(sync.Mutex)
+	return &boundedQueue[T]{lock: lock, cond: sync.NewCond(lock), items: make([]T, 0, capacity), capacity: capacity, overflow: overflow}
+}
+
+// adds the item applying the overflow policy if the queue is full - returns FALSE if the queue is closed already
+func (q *boundedQueue[T]) push(item T) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for q.overflow == _OVERFLOW_BLOCK && len(q.items) >= q.capacity && !q.closed {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return false
+	}
+	if len(q.items) >= q.capacity {
+		q.dropped++
+		q.droppedTotal++
+		if q.overflow == _OVERFLOW_DROP_NEWEST {
+			return true
+		}
+		var zero T
+		q.items[0] = zero
+		q.items = q.items[1:]
+	}
+	q.items = append(q.items, item)
+	q.cond.Broadcast()
+	return true
+}
+
+// waits for the next item - returns FALSE if the queue is closed and there are no more items
+func (q *boundedQueue[T]) pop() (T, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for len(q.items) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if len(q.items) == 0 {
+		var zero T
+		return zero, false
+	}
+	item := q.items[0]
+	var zero T
+	q.items[0] = zero
+	q.items = q.items[1:]
+	q.inProgress++
+	q.cond.Broadcast()
+	return item, true
+}
+
+// marks a popped item as processed
+func (q *boundedQueue[T]) done() {
+	q.lock.Lock()
+	q.inProgress--
+	q.cond.Broadcast()
+	q.lock.Unlock()
+}
+
+// waits until all pushed items are processed - or until abort() returns TRUE. abort() is re-evaluated on every change of the
+// queue and whenever notify() is invoked. Returns the number of items not processed yet.
+func (q *boundedQueue[T]) waitIdle(abort func() bool) int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for len(q.items)+q.inProgress > 0 && !abort() {
+		q.cond.Wait()
+	}
+	return len(q.items) + q.inProgress
+}
+
+// wakes up the goroutines waiting in waitIdle() so they re-evaluate their abort condition
+func (q *boundedQueue[T]) notify() {
+	q.lock.Lock()
+	q.cond.Broadcast()
+	q.lock.Unlock()
+}
+
+// no more items are accepted - the items already in the queue can still be popped
+func (q *boundedQueue[T]) close() {
+	q.lock.Lock()
+	q.closed = true
+	q.cond.Broadcast()
+	q.lock.Unlock()
+}
+
+func (q *boundedQueue[T]) isClosed() bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.closed
+}
+
+// returns the number of items dropped due to overflow since the last invocation
+func (q *boundedQueue[T]) takeDropped() uint64 {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	dropped := q.dropped
+	q.dropped = 0
+	return dropped
+}
+
+// returns the number of items dropped due to overflow since the queue was created
+func (q *boundedQueue[T]) getDroppedTotal() uint64 {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.droppedTotal
+}
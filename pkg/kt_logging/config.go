@@ -16,18 +16,24 @@ type LoggerConfigModel struct {
 	Name         string   `json:"name" yaml:"name"`
 	Level        string   `json:"level" yaml:"level"`
 	HandlerNames []string `json:"handlers" yaml:"handlers"`
+	// collapses repeated log events of the Logger
+	Dedup *DedupModel `json:"dedup" yaml:"dedup"`
+	// limits the rate of log events of the Logger
+	RateLimit *RateLimitModel `json:"rateLimit" yaml:"rateLimit"`
 }
 
 type RollingFileModel struct {
-	// File is the file path to write logs to.
+	// File is the file path to write logs to. Either File or FilePattern must
+	// be given.
 	File string `json:"file" yaml:"file"`
 
 	// MaxSize is the maximum size in megabytes of the log file before it gets
-	// rotated. It defaults to 100 megabytes.
+	// rotated. It defaults to 100 megabytes - or no size limit if RotateEvery
+	// or ReopenOnSignal is given.
 	MaxSizeMb int `json:"maxSizeMb" yaml:"maxSizeMb"`
 
 	// MaxAge is the maximum number of days to retain old log files based on the
-	// timestamp encoded in their filename.  Note that a day is defined as 24
+	// time they were last written.  Note that a day is defined as 24
 	// hours and may not exactly correspond to calendar days due to daylight
 	// savings, leap seconds, etc. The default is not to remove old log files
 	// based on age.
@@ -41,14 +47,332 @@ type RollingFileModel struct {
 	// Compress determines if the rotated log files should be compressed
 	// using gzip. The default is not to perform compression.
 	Compress bool `json:"compress" yaml:"compress"`
+
+	// RotateEvery enables time based rotation: "hourly", "daily" or a duration
+	// like "30m" or "6h" (durations are aligned to midnight). It can be combined
+	// with MaxSizeMb - whichever comes first triggers the rotation. The default
+	// is no time based rotation.
+	RotateEvery string `json:"rotateEvery" yaml:"rotateEvery"`
+
+	// FilePattern can be used instead of File - the file name is generated from
+	// the start time of the rotation period with the placeholders %Y (year),
+	// %m (month), %d (day), %H (hour), %M (minute), %S (second) e.g.
+	// "logs/app-%Y-%m-%d.log". If the file also exceeds MaxSizeMb within the
+	// period then the next parts are named "app-2024-01-31.1.log" etc.
+	FilePattern string `json:"filePattern" yaml:"filePattern"`
+
+	// UTC determines if rotation boundaries and the times in file names are
+	// in UTC. The default is local time.
+	UTC bool `json:"utc" yaml:"utc"`
+
+	// FileMode is the permissions of the log files in octal e.g. "0640". It is
+	// applied regardless of the umask. The default is "0644" (umask applies).
+	FileMode string `json:"fileMode" yaml:"fileMode"`
+
+	// ReopenOnSignal makes the file reopened when the process receives SIGHUP -
+	// for files rotated by an external tool e.g. logrotate with 'create' style
+	// (move the file then signal). Files truncated with 'copytruncate' style
+	// need no signal - they are written in append mode. If neither MaxSizeMb
+	// nor RotateEvery is given then the file is not rotated internally.
+	ReopenOnSignal bool `json:"reopenOnSignal" yaml:"reopenOnSignal"`
+}
+
+// config of the 'network' output of the "zap" handler type - streams the encoded log events over TCP or UDP
+type NetworkModel struct {
+	// "tcp" or "udp"
+	Protocol string `json:"protocol" yaml:"protocol"`
+
+	// Address of the endpoint e.g. "localhost:24224"
+	Address string `json:"address" yaml:"address"`
+
+	// How log events are separated on TCP: "newline" or "octet-counting" (length prefixed). The default is "newline". On UDP every
+	// log event is sent in its own datagram.
+	Framing string `json:"framing" yaml:"framing"`
+
+	// Number of log events kept in memory while they are waiting to be sent (e.g. while the endpoint is not reachable). The default
+	// is 1000.
+	BufferSize int `json:"bufferSize" yaml:"bufferSize"`
+
+	// What happens if the buffer is full: "dropOldest", "dropNewest" or "block" (the logging goroutine waits until there is space
+	// in the buffer). The default is "dropOldest".
+	Overflow string `json:"overflow" yaml:"overflow"`
+
+	// Reconnect attempts are done with exponential backoff - this is the maximum delay between two attempts in seconds. The default
+	// is 30 seconds.
+	MaxReconnectDelaySec int `json:"maxReconnectDelaySec" yaml:"maxReconnectDelaySec"`
+}
+
+// the 'settings' of the "syslog" handler type
+type SyslogModel struct {
+	// How to reach the syslog server: "unixgram", "unix", "udp", "tcp" or "tls". If both Network and Address are omitted then the
+	// local syslog socket is used (e.g. /dev/log)
+	Network string `json:"network" yaml:"network"`
+
+	// Address of the syslog server e.g. "localhost:514" - or the socket path in case of unix sockets
+	Address string `json:"address" yaml:"address"`
+
+	// The syslog facility e.g. "user", "daemon", "local0" ... "local7". The default is "user".
+	Facility string `json:"facility" yaml:"facility"`
+
+	// The APP-NAME (or TAG in RFC 3164) of the messages. The default is the name of the executable.
+	AppName string `json:"appName" yaml:"appName"`
+
+	// The HOSTNAME of the messages. The default is the hostname of the machine.
+	Hostname string `json:"hostname" yaml:"hostname"`
+
+	// Message format: "rfc5424" or "rfc3164". The default is "rfc5424".
+	Format string `json:"format" yaml:"format"`
+
+	// Only for stream ("unix", "tcp", "tls") networks - how messages are separated: "octet-counting" or "newline".
+	// The default is "octet-counting".
+	Framing string `json:"framing" yaml:"framing"`
+
+	// The SD-ID of the structured data element labels are rendered into (RFC 5424 only). The default is "labels@32473".
+	StructuredDataID string `json:"structuredDataId" yaml:"structuredDataId"`
+
+	// Timeout of connecting to the syslog server in milliseconds. The default is 5000.
+	DialTimeoutMs int `json:"dialTimeoutMs" yaml:"dialTimeoutMs"`
+
+	// Timeout of writing one message in milliseconds - if the server does not take it in time the message is dropped and the
+	// connection is re-established. The default is 5000.
+	WriteTimeoutMs int `json:"writeTimeoutMs" yaml:"writeTimeoutMs"`
+}
+
+// batching settings of the handler types shipping log events in batches (e.g. "http")
+type BatchingModel struct {
+	// A batch is sent once it contains this many log events. The default is 100.
+	BatchSize int `json:"batchSize" yaml:"batchSize"`
+
+	// A batch is sent once its size reaches this many bytes. The default is 1048576 (1MB).
+	BatchBytes int `json:"batchBytes" yaml:"batchBytes"`
+
+	// A not empty batch is sent at latest after this many milliseconds. The default is 1000.
+	FlushIntervalMs int `json:"flushIntervalMs" yaml:"flushIntervalMs"`
+
+	// How many times sending a batch is retried (with exponential backoff) on network errors or 5xx responses before the batch
+	// is dropped. The default is 3, use -1 to disable retries.
+	MaxRetries int `json:"maxRetries" yaml:"maxRetries"`
+
+	// Number of batches kept in memory while they are waiting to be sent (e.g. while the endpoint is not reachable). The default
+	// is 10.
+	MaxPendingBatches int `json:"maxPendingBatches" yaml:"maxPendingBatches"`
+
+	// What happens if there are too many pending batches: "dropOldest", "dropNewest" or "block" (the logging goroutine waits
+	// until a batch is sent). The default is "dropOldest".
+	Overflow string `json:"overflow" yaml:"overflow"`
+}
+
+// the 'settings' of the "http" handler type - POSTs batches of JSON encoded log events
+type HttpModel struct {
+	BatchingModel `yaml:",inline"`
+
+	// The URL batches are sent to
+	Url string `json:"url" yaml:"url"`
+
+	// The HTTP method. The default is "POST".
+	Method string `json:"method" yaml:"method"`
+
+	// Additional request headers e.g. "Authorization"
+	Headers map[string]string `json:"headers" yaml:"headers"`
+
+	// Body format: "ndjson" (one JSON document per line) or "json" (JSON array). The default is "ndjson".
+	Format string `json:"format" yaml:"format"`
+
+	// If TRUE then the request body is gzip compressed
+	Gzip bool `json:"gzip" yaml:"gzip"`
+
+	// Timeout of one request in seconds. The default is 10 seconds.
+	TimeoutSec int `json:"timeoutSec" yaml:"timeoutSec"`
+}
+
+// the 'settings' of the "loki" handler type - pushes batches of log events to Grafana Loki
+type LokiModel struct {
+	BatchingModel `yaml:",inline"`
+
+	// The push API URL e.g. "http://localhost:3100/loki/api/v1/push"
+	Url string `json:"url" yaml:"url"`
+
+	// Sent as X-Scope-OrgID header - if Loki runs in multi-tenant mode
+	TenantID string `json:"tenantId" yaml:"tenantId"`
+
+	// Additional request headers e.g. "Authorization"
+	Headers map[string]string `json:"headers" yaml:"headers"`
+
+	// Label keys which become Loki stream labels - can be keys of global labels, event labels and "logger" and "level" too. Labels
+	// not listed here stay in the log line. The default is all the global labels and "level".
+	StreamLabels []string `json:"streamLabels" yaml:"streamLabels"`
+
+	// The maximum number of different streams (label value combinations) the handler creates. Once it is reached log events which
+	// would open a new stream go into a stream having only the global labels (and "overflow" label) - their stream labels are
+	// kept in the log line. The default is 1000.
+	MaxStreams int `json:"maxStreams" yaml:"maxStreams"`
+
+	// If TRUE then the request body is gzip compressed
+	Gzip bool `json:"gzip" yaml:"gzip"`
+
+	// Timeout of one request in seconds. The default is 10 seconds.
+	TimeoutSec int `json:"timeoutSec" yaml:"timeoutSec"`
+}
+
+// the 'settings' of the "elasticsearch" handler type - indexes batches of log events with the _bulk API of Elasticsearch / OpenSearch
+type ElasticsearchModel struct {
+	BatchingModel `yaml:",inline"`
+
+	// The base URL of the cluster e.g. "http://localhost:9200" - requests go to <url>/_bulk
+	Url string `json:"url" yaml:"url"`
+
+	// The name of the index - can contain date placeholders which are resolved from the (UTC) time of the log event: %Y (year),
+	// %m (month), %d (day), %H (hour) and %% for a literal '%'. The default is "logs-%Y.%m.%d".
+	Index string `json:"index" yaml:"index"`
+
+	// The bulk action: "index" or "create" (needed for data streams). The default is "index".
+	Action string `json:"action" yaml:"action"`
+
+	// Credentials for basic authentication
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+
+	// The API key (base64 encoded "id:key") - sent in the "Authorization: ApiKey ..." header
+	ApiKey string `json:"apiKey" yaml:"apiKey"`
+
+	// Additional request headers
+	Headers map[string]string `json:"headers" yaml:"headers"`
+
+	// If TRUE then the request body is gzip compressed
+	Gzip bool `json:"gzip" yaml:"gzip"`
+
+	// Timeout of one request in seconds. The default is 10 seconds.
+	TimeoutSec int `json:"timeoutSec" yaml:"timeoutSec"`
+}
+
+// the 'settings' of the "gelf" handler type - sends log events to Graylog in GELF 1.1 format
+type GelfModel struct {
+	// "udp" or "tcp". The default is "udp".
+	Protocol string `json:"protocol" yaml:"protocol"`
+
+	// Address of the GELF input e.g. "localhost:12201"
+	Address string `json:"address" yaml:"address"`
+
+	// Only for UDP: "gzip", "zlib" or "none". The default is "gzip". (GELF over TCP does not support compression.)
+	Compression string `json:"compression" yaml:"compression"`
+
+	// Only for UDP: messages bigger than this many bytes are sent in chunks. The default is 1420 which fits into the usual MTU.
+	ChunkSize int `json:"chunkSize" yaml:"chunkSize"`
+
+	// The "host" field of the messages. The default is the hostname of the machine.
+	Host string `json:"host" yaml:"host"`
+
+	// Only for TCP: timeout of connecting to the GELF input in milliseconds. The default is 5000.
+	DialTimeoutMs int `json:"dialTimeoutMs" yaml:"dialTimeoutMs"`
+
+	// Timeout of writing one message in milliseconds - if the GELF input does not take it in time the message is dropped and the
+	// connection is re-established. The default is 5000.
+	WriteTimeoutMs int `json:"writeTimeoutMs" yaml:"writeTimeoutMs"`
+}
+
+// the 'settings' of the "journald" handler type - writes log events into the systemd journal with structured fields
+type JournaldModel struct {
+	// The native socket of journald. The default is "/run/systemd/journal/socket".
+	SocketPath string `json:"socketPath" yaml:"socketPath"`
+
+	// The SYSLOG_IDENTIFIER field of the entries. The default is the name of the executable.
+	Identifier string `json:"identifier" yaml:"identifier"`
+
+	// The name of the field the logger name goes into. The default is "LOGGER".
+	LoggerField string `json:"loggerField" yaml:"loggerField"`
+}
+
+// the 'settings' of the "memory" handler type - keeps the most recent log events in memory (see RecentEvents())
+type MemoryModel struct {
+	// The max number of events kept. The default is 1000 if MaxBytes is not given either.
+	MaxEvents int `json:"maxEvents" yaml:"maxEvents"`
+
+	// The max (approximate) size of the kept events in bytes. The default is no size limit.
+	MaxBytes int `json:"maxBytes" yaml:"maxBytes"`
+}
+
+// config of the 'fingersCrossed' option of the handlers - log events are held back in memory per scope (see ContextWithLogBuffer())
+// and written only if an event on the trigger level happens in the same scope, otherwise they are discarded
+type FingersCrossedModel struct {
+	// Events on this level or more severe trigger writing the held back events. The default is "error".
+	TriggerLevel string `json:"triggerLevel" yaml:"triggerLevel"`
+
+	// The max number of events held back per scope - if more events come the oldest ones are discarded. The default is 100.
+	BufferSize int `json:"bufferSize" yaml:"bufferSize"`
+
+	// What happens after the trigger: if FALSE (default) the subsequent events of the scope are written right away, if TRUE they
+	// are held back again until the next trigger. The events fired without scope are always held back again.
+	BufferAfterTrigger bool `json:"bufferAfterTrigger" yaml:"bufferAfterTrigger"`
+}
+
+// config of the 'sampling' option of the handlers - protects against log floods. Log events are counted per level + message
+// template within each tick: the first Initial events are written, after that every Thereafter-th. At the end of each tick a
+// summary event is written about the dropped ones.
+type SamplingModel struct {
+	// The number of events of the same kind written in each tick. The default is 100.
+	Initial int `json:"initial" yaml:"initial"`
+
+	// After the initial ones every Thereafter-th event of the same kind is written. The default is 100, use -1 to drop all.
+	Thereafter int `json:"thereafter" yaml:"thereafter"`
+
+	// The length of the tick as Go duration e.g. "1s" or "500ms". The default is 1 second.
+	Tick string `json:"tick" yaml:"tick"`
+}
+
+// config of the 'dedup' option of Loggers and handlers - identical log events (same logger, level, message template and labels)
+// within the window are collapsed: the first one is written right away, when the window closes one event is written about the
+// repetitions with labels 'repeated', 'firstTime' and 'lastTime'
+type DedupModel struct {
+	// The length of the window as Go duration e.g. "10s". The default is 10 seconds.
+	Window string `json:"window" yaml:"window"`
+
+	// The max number of different events tracked at the same time - events above this are not deduplicated. The default is 10000.
+	MaxEntries int `json:"maxEntries" yaml:"maxEntries"`
+}
+
+// config of the 'rateLimit' option of Loggers - a token bucket, events above the limit are dropped
+type RateLimitModel struct {
+	// The number of log events per second allowed on average. Mandatory.
+	EventsPerSec float64 `json:"eventsPerSec" yaml:"eventsPerSec"`
+
+	// The number of log events allowed in a burst. The default is EventsPerSec (rounded up).
+	Burst int `json:"burst" yaml:"burst"`
+}
+
+// config of the 'async' option of the handlers - log events are queued and written by background goroutines so the logging
+// goroutine does not wait for encoding and writing
+type AsyncModel struct {
+	// Number of log events the queue can hold. The default is 1000.
+	QueueSize int `json:"queueSize" yaml:"queueSize"`
+
+	// What happens if the queue is full: "dropOldest", "dropNewest" or "block" (the logging goroutine waits until there is space
+	// in the queue). The default is "dropOldest".
+	Overflow string `json:"overflow" yaml:"overflow"`
+
+	// Number of goroutines writing the log events. The default is 1. Please note: with more than 1 worker the order of the log
+	// events in the output is not guaranteed.
+	Workers int `json:"workers" yaml:"workers"`
 }
 
 // for json/yaml config file parsing - this is the entries in /handlers path
 type HandlerConfigModel struct {
+	// the type of the handler - see RegisterHandlerType(). If omitted then the built-in "zap" type is used
+	Type        string            `json:"type" yaml:"type"`
 	Level       string            `json:"level" yaml:"level"`
 	Encoding    string            `json:"encoding" yaml:"encoding"`
 	OutputPaths []string          `json:"outputPaths" yaml:"outputPaths"`
 	RollingFile *RollingFileModel `json:"rollingFile" yaml:"rollingFile"`
+	Network     *NetworkModel     `json:"network" yaml:"network"`
+	// holds back log events until an event on the trigger level happens - works with any handler type
+	FingersCrossed *FingersCrossedModel `json:"fingersCrossed" yaml:"fingersCrossed"`
+	// drops log events of the same kind above a rate - works with any handler type
+	Sampling *SamplingModel `json:"sampling" yaml:"sampling"`
+	// collapses repeated log events - works with any handler type
+	Dedup *DedupModel `json:"dedup" yaml:"dedup"`
+	// makes the handler asynchronous - works with any handler type
+	Async *AsyncModel `json:"async" yaml:"async"`
+	// settings of the handler type (all types except "zap") - see HandlerConfigModel.DecodeSettings()
+	Settings map[string]any `json:"settings" yaml:"settings"`
 }
 
 // for json/yaml config file parsing - this is root level object
@@ -0,0 +1,94 @@
+// This file contains the hot-reload support of the config file
+//
+// Once WatchConfig() is invoked the config file is checked periodically and reloaded if it has been changed. With
+// ReloadConfigOnSignal() the reload can also be forced by sending SIGHUP to the process - this is opt-in as log rotation tools
+// (e.g. logrotate) are sending SIGHUP too, see the 'reopenOnSignal' option of rolling files.
+
+package kt_logging
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const _DEFAULT_WATCH_INTERVAL = 2 * time.Second
+
+// Starts watching the .yaml or .json config file on the given path and re-applies it (see ReloadFromConfig()) whenever its
+// content changes. The file is polled with the given interval - if you pass 0 then the default (2 seconds) is used.
+// If a reload fails (e.g. the file was saved with a typo) the current setup remains active and the problem is logged with the
+// "root" logger.
+// Returns a function you can invoke to stop watching.
+func WatchConfig(cfgPath string, pollInterval time.Duration) (func(), error) {
+	if pollInterval <= 0 {
+		pollInterval = _DEFAULT_WATCH_INTERVAL
+	}
+	lastStat, err := os.Stat(cfgPath)
+	if err != nil {
+		return nil, err
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	done := make(chan struct{})
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				stat, err := os.Stat(cfgPath)
+				if err != nil {
+					// the file might be just being replaced - let's try next time
+					continue
+				}
+				if stat.ModTime().Equal(lastStat.ModTime()) && stat.Size() == lastStat.Size() {
+					continue
+				}
+				lastStat = stat
+				reloadWatchedConfig(cfgPath)
+			}
+		}
+	}()
+
+	var stopOnce sync.Once
+	return func() {
+		stopOnce.Do(func() { close(done) })
+	}, nil
+}
+
+// Re-applies the .yaml or .json config file on the given path (see ReloadFromConfig()) whenever the process receives a SIGHUP
+// signal. If a reload fails the current setup remains active and the problem is logged with the "root" logger.
+// Please note: if you also have rolling files with 'reopenOnSignal' then a SIGHUP sent by logrotate reloads the whole config too.
+// Returns a function you can invoke to stop listening to the signal.
+func ReloadConfigOnSignal(cfgPath string) func() {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	done := make(chan struct{})
+
+	go func() {
+		defer signal.Stop(sighup)
+		for {
+			select {
+			case <-done:
+				return
+			case <-sighup:
+				reloadWatchedConfig(cfgPath)
+			}
+		}
+	}()
+
+	var stopOnce sync.Once
+	return func() {
+		stopOnce.Do(func() { close(done) })
+	}
+}
+
+func reloadWatchedConfig(cfgPath string) {
+	if err := ReloadFromConfig(cfgPath); err != nil {
+		GetLogger(_ROOT_NAME).Error("failed to reload log config from '%v' - keeping the current setup. error was: %v", cfgPath, err)
+	}
+}
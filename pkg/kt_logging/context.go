@@ -0,0 +1,107 @@
+// This file contains context.Context support - so labels can be attached to a request (or any unit of work) and appear on every
+// log event fired with that context deeper in the call stack
+//
+//	ctx = kt_logging.ContextWithLabels(ctx, kt_logging.StringLabel("requestId", requestId))
+//	...
+//	logger.Ctx(ctx).Info("this log event carries the 'requestId' label")
+
+package kt_logging
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// the key we store the labels in the context with
+type contextLabelsKey struct{}
+
+// A ContextLabelExtractor can derive labels from a context.Context - e.g. the trace and span id of the current tracing span
+type ContextLabelExtractor func(ctx context.Context) []Label
+
+// the registered extractors by name - see RegisterContextLabelExtractor()
+var contextLabelExtractors = map[string]ContextLabelExtractor{}
+
+// the registered extractors in order of their names - so they are always invoked in the same order
+var orderedContextLabelExtractors = []ContextLabelExtractor{}
+var contextLabelExtractorsLock = new(sync.RWMutex)
+
+// Registers an extractor under the given name - which is invoked with the context of every log event fired with a context (see
+// Logger.Ctx() and LogEvent.WithContext()) and the labels it returns are added to the event. Registering a new extractor with an
+// already used name replaces the former one, registering nil removes it.
+func RegisterContextLabelExtractor(name string, extractor ContextLabelExtractor) {
+	contextLabelExtractorsLock.Lock()
+	defer contextLabelExtractorsLock.Unlock()
+
+	if extractor == nil {
+		delete(contextLabelExtractors, name)
+	} else {
+		contextLabelExtractors[name] = extractor
+	}
+	names := make([]string, 0, len(contextLabelExtractors))
+	for extractorName := range contextLabelExtractors {
+		names = append(names, extractorName)
+	}
+	sort.Strings(names)
+	ordered := make([]ContextLabelExtractor, 0, len(names))
+	for _, extractorName := range names {
+		ordered = append(ordered, contextLabelExtractors[extractorName])
+	}
+	orderedContextLabelExtractors = ordered
+}
+
+// Returns a copy of the context which carries the given labels on top of the labels the parent context (might) already carry.
+// If a label with the same key was already added by a parent context then the new value overrides it.
+func ContextWithLabels(ctx context.Context, labels ...Label) context.Context {
+	existingLabels := LabelsFromContext(ctx)
+	joinedLabels := make([]Label, 0, len(existingLabels)+len(labels))
+	for _, existingLabel := range existingLabels {
+		if !containsLabelKey(labels, existingLabel.key) {
+			joinedLabels = append(joinedLabels, existingLabel)
+		}
+	}
+	joinedLabels = append(joinedLabels, labels...)
+	return context.WithValue(ctx, contextLabelsKey{}, joinedLabels)
+}
+
+// Returns the labels attached to the context with ContextWithLabels() - or empty array if there are none
+func LabelsFromContext(ctx context.Context) []Label {
+	if ctx == nil {
+		return []Label{}
+	}
+	labels, ok := ctx.Value(contextLabelsKey{}).([]Label)
+	if !ok {
+		return []Label{}
+	}
+	return labels
+}
+
+// returns all the labels of the context - the ones attached with ContextWithLabels() and the ones derived by the registered
+// extractors (see RegisterContextLabelExtractor())
+func allLabelsOfContext(ctx context.Context) []Label {
+	if ctx == nil {
+		return []Label{}
+	}
+	contextLabelExtractorsLock.RLock()
+	extractors := orderedContextLabelExtractors
+	contextLabelExtractorsLock.RUnlock()
+
+	labels := LabelsFromContext(ctx)
+	if len(extractors) == 0 {
+		return labels
+	}
+	joinedLabels := append([]Label{}, labels...)
+	for _, extractor := range extractors {
+		joinedLabels = append(joinedLabels, extractor(ctx)...)
+	}
+	return joinedLabels
+}
+
+func containsLabelKey(labels []Label, key string) bool {
+	for _, label := range labels {
+		if label.key == key {
+			return true
+		}
+	}
+	return false
+}
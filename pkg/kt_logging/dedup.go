@@ -0,0 +1,179 @@
+// This file contains the deduplication of repeated log events - configurable on Loggers and on handlers ('dedup' option)
+//
+// Identical log events (same logger, level, message template and labels) within a window are collapsed: the first one is written
+// right away, the repetitions are held back and when the window closes one event is written about them - the last repetition
+// with labels 'repeated' (number of repetitions after the first event), 'firstTime' and 'lastTime'.
+
+package kt_logging
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	_DEFAULT_DEDUP_WINDOW      = 10 * time.Second
+	_DEFAULT_DEDUP_MAX_ENTRIES = 10000
+)
+
+type deduplicator struct {
+	window     time.Duration
+	maxEntries int
+	lock       *sync.Mutex
+	// the events seen in their window - by dedup key
+	pending map[string]*dedupEntry
+}
+
+type dedupEntry struct {
+	firstTime time.Time
+	// the last repetition
+	last     LogRecord
+	repeated int
+	timer    *time.Timer
+	// writes the summary event
+	emit func(record LogRecord)
+}
+
+func newDeduplicator(model DedupModel) (*deduplicator, error) {
+	window := _DEFAULT_DEDUP_WINDOW
+	if model.Window != "" {
+		var err error
+		if window, err = time.ParseDuration(model.Window); err != nil || window <= 0 {
+			return nil, fmt.Errorf("invalid dedup 'window' value '%v' - positive duration is expected e.g. \"10s\"", model.Window)
+		}
+	}
+	return &deduplicator{
+		window:     window,
+		maxEntries: withDefault(model.MaxEntries, _DEFAULT_DEDUP_MAX_ENTRIES),
+		lock:       new(sync.Mutex),
+		pending:    map[string]*dedupEntry{},
+	}, nil
+}
+
+// returns TRUE if the event should be written now - FALSE if it is a repetition which is held back. The summary of the
+// repetitions is written later with the given emit function.
+func (d *deduplicator) check(record LogRecord, emit func(record LogRecord)) bool {
+	if record.Level == PanicLevel || record.Level == FatalLevel {
+		// the last words are never held back
+		return true
+	}
+	key := dedupKeyOf(record)
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if entry, found := d.pending[key]; found {
+		entry.repeated++
+		entry.last = record
+		return false
+	}
+	if len(d.pending) >= d.maxEntries {
+		// too many different events - we do not track more
+		return true
+	}
+	entry := &dedupEntry{firstTime: record.Time, emit: emit}
+	entry.timer = time.AfterFunc(d.window, func() { d.expire(key, entry) })
+	d.pending[key] = entry
+	return true
+}
+
+// the window of the entry is over - writes the summary of the repetitions (if any)
+func (d *deduplicator) expire(key string, entry *dedupEntry) {
+	d.lock.Lock()
+	if d.pending[key] != entry {
+		// flushed meanwhile
+		d.lock.Unlock()
+		return
+	}
+	delete(d.pending, key)
+	d.lock.Unlock()
+	entry.writeSummary()
+}
+
+// closes all windows right now - writes the summaries of the repetitions
+func (d *deduplicator) flush() {
+	d.lock.Lock()
+	entries := make([]*dedupEntry, 0, len(d.pending))
+	for _, entry := range d.pending {
+		entry.timer.Stop()
+		entries = append(entries, entry)
+	}
+	d.pending = map[string]*dedupEntry{}
+	d.lock.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].firstTime.Before(entries[j].firstTime) })
+	for _, entry := range entries {
+		entry.writeSummary()
+	}
+}
+
+// NOT THREAD SAFE! Assumes the entry is not in the pending ones anymore
+func (entry *dedupEntry) writeSummary() {
+	if entry.repeated == 0 {
+		return
+	}
+	summary := entry.last
+	labels := make([]Label, 0, len(summary.Labels)+3)
+	labels = append(labels, summary.Labels...)
+	labels = append(labels,
+		IntLabel("repeated", int64(entry.repeated)),
+		StringLabel("firstTime", entry.firstTime.Format(time.RFC3339Nano)),
+		StringLabel("lastTime", entry.last.Time.Format(time.RFC3339Nano)),
+	)
+	summary.Labels = labels
+	entry.emit(summary)
+}
+
+// events with the same key are identical - logger, level, message template and labels (in any order)
+func dedupKeyOf(record LogRecord) string {
+	labels := make([]string, 0, len(record.Labels))
+	for _, label := range record.Labels {
+		labels = append(labels, fmt.Sprintf("%v\x00%d\x00%v", label.key, label._type, label.valueAsString()))
+	}
+	sort.Strings(labels)
+	return fmt.Sprintf("%v\x01%d\x01%v\x01%v", record.LoggerName, record.Level, record.MessageTemplate, strings.Join(labels, "\x01"))
+}
+
+// a Handler wrapper - the 'dedup' option of the handlers
+type dedupHandler struct {
+	handler      Handler
+	name         string
+	deduplicator *deduplicator
+}
+
+func newDedupHandler(handlerName string, handler Handler, model DedupModel) (*dedupHandler, error) {
+	deduplicator, err := newDeduplicator(model)
+	if err != nil {
+		return nil, err
+	}
+	return &dedupHandler{handler: handler, name: handlerName, deduplicator: deduplicator}, nil
+}
+
+func (h *dedupHandler) Handle(record LogRecord) error {
+	if !h.deduplicator.check(record, h.emit) {
+		return nil
+	}
+	return h.handler.Handle(record)
+}
+
+func (h *dedupHandler) emit(record LogRecord) {
+	if err := h.handler.Handle(record); err != nil {
+		reportHandlerError(h.name, err)
+	}
+}
+
+func (h *dedupHandler) Sync() error {
+	return h.handler.Sync()
+}
+
+// writes the summaries of the open windows then closes the wrapped Handler
+func (h *dedupHandler) Close() error {
+	h.deduplicator.flush()
+	return h.handler.Close()
+}
+
+func (h *dedupHandler) unwrap() Handler {
+	return h.handler
+}
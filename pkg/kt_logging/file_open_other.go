@@ -0,0 +1,10 @@
+//go:build !windows
+
+package kt_logging
+
+import "os"
+
+// opens the file for appending - creates it if does not exist
+func openLogFile(path string, mode os.FileMode) (*os.File, error) {
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, mode)
+}
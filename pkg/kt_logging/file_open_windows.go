@@ -0,0 +1,38 @@
+//go:build windows
+
+package kt_logging
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// opens the file for appending - creates it if does not exist
+// os.OpenFile() does not allow others to rename / delete the file while it is open - this is why rotation of files is failing on
+// Windows with "The process cannot access the file because it is being used by another process." if anything else (e.g.
+// another handler or a log viewer) keeps the file open. So we open it with FILE_SHARE_DELETE.
+func openLogFile(path string, mode os.FileMode) (*os.File, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	var attributes uint32 = windows.FILE_ATTRIBUTE_NORMAL
+	if mode&0200 == 0 {
+		attributes = windows.FILE_ATTRIBUTE_READONLY
+	}
+	handle, err := windows.CreateFile(
+		pathPtr,
+		// append only access - so every write goes to the end of the file
+		windows.FILE_APPEND_DATA|windows.FILE_READ_ATTRIBUTES|windows.FILE_WRITE_ATTRIBUTES|windows.SYNCHRONIZE,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		nil,
+		windows.OPEN_ALWAYS,
+		attributes,
+		0,
+	)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	return os.NewFile(uintptr(handle), path), nil
+}
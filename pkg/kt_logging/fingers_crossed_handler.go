@@ -0,0 +1,149 @@
+// This file contains the 'fingersCrossed' option of the handlers - log events are held back in memory and written only if an event
+// on the trigger level (e.g. error) happens in the same scope, otherwise they are discarded
+//
+// A scope is a context created with ContextWithLogBuffer() - typically one per request:
+//
+//	ctx = kt_logging.ContextWithLogBuffer(ctx)
+//	...
+//	logger.Ctx(ctx).Debug("held back - written only if this request fails")
+//	logger.Ctx(ctx).Error("this writes the held back events of the request then itself")
+//
+// The buffers live in the context - so once the request is done and its context is gone, the held back events are gone too.
+// Log events fired without such a context share one handler-wide buffer. This one is never switched off by a trigger (regardless
+// of 'bufferAfterTrigger') - otherwise one error anywhere in the app would switch off the fingers crossed for good.
+
+package kt_logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const _DEFAULT_FINGERS_CROSSED_BUFFER_SIZE = 100
+
+// the key we store the log buffer scope in the context with
+type logBufferScopeKey struct{}
+
+// the buffers of one scope - per handler as handlers might have different triggers
+type logBufferScope struct {
+	lock    *sync.Mutex
+	buffers map[*fingersCrossedHandler]*fingersCrossedBuffer
+}
+
+// Returns a copy of the context which is a new scope for the handlers with 'fingersCrossed' option - the log events fired with
+// this context (see Logger.Ctx() and LogEvent.WithContext()) are held back until an event on the trigger level is fired with it
+func ContextWithLogBuffer(ctx context.Context) context.Context {
+	scope := &logBufferScope{lock: new(sync.Mutex), buffers: map[*fingersCrossedHandler]*fingersCrossedBuffer{}}
+	return context.WithValue(ctx, logBufferScopeKey{}, scope)
+}
+
+type fingersCrossedHandler struct {
+	handler            Handler
+	triggerLevel       LogLevel
+	bufferSize         int
+	bufferAfterTrigger bool
+	// for the events fired without a scope
+	defaultBuffer *fingersCrossedBuffer
+}
+
+// the held back events of one scope
+type fingersCrossedBuffer struct {
+	lock    *sync.Mutex
+	records []LogRecord
+	// TRUE once the trigger event happened (and we are not buffering after trigger)
+	triggered bool
+}
+
+// wraps the Handler so it gets the log events only if the trigger level is reached
+func newFingersCrossedHandler(handler Handler, model FingersCrossedModel) (*fingersCrossedHandler, error) {
+	triggerLevel := ErrorLevel
+	if model.TriggerLevel != "" {
+		var err error
+		if triggerLevel, err = parseLogLevelString(model.TriggerLevel); err != nil {
+			return nil, fmt.Errorf("invalid 'triggerLevel': %v", err)
+		}
+		if triggerLevel == NoneLevel {
+			return nil, fmt.Errorf("invalid 'triggerLevel': nothing would trigger")
+		}
+	}
+	return &fingersCrossedHandler{
+		handler:            handler,
+		triggerLevel:       triggerLevel,
+		bufferSize:         withDefault(model.BufferSize, _DEFAULT_FINGERS_CROSSED_BUFFER_SIZE),
+		bufferAfterTrigger: model.BufferAfterTrigger,
+		defaultBuffer:      newFingersCrossedBuffer(),
+	}, nil
+}
+
+func newFingersCrossedBuffer() *fingersCrossedBuffer {
+	return &fingersCrossedBuffer{lock: new(sync.Mutex)}
+}
+
+func (h *fingersCrossedHandler) Handle(record LogRecord) error {
+	buffer := h.bufferOf(record.Context)
+
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+
+	if buffer.triggered {
+		return h.handler.Handle(record)
+	}
+	if record.Level.filterLevel() > h.triggerLevel.filterLevel() {
+		if len(buffer.records) >= h.bufferSize {
+			// the oldest ones are the least interesting
+			buffer.records[0] = LogRecord{}
+			buffer.records = buffer.records[1:]
+		}
+		buffer.records = append(buffer.records, record)
+		return nil
+	}
+
+	// triggered! let's write what we held back - then the event itself
+	errs := []error{}
+	for _, buffered := range buffer.records {
+		if err := h.handler.Handle(buffered); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	buffer.records = nil
+	// the handler-wide buffer is not a request - it lives as long as the app, so it always starts buffering again
+	buffer.triggered = !h.bufferAfterTrigger && buffer != h.defaultBuffer
+	if err := h.handler.Handle(record); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (h *fingersCrossedHandler) Sync() error {
+	return h.handler.Sync()
+}
+
+// the held back events are discarded
+func (h *fingersCrossedHandler) Close() error {
+	return h.handler.Close()
+}
+
+func (h *fingersCrossedHandler) unwrap() Handler {
+	return h.handler
+}
+
+// returns the buffer of the scope the context belongs to - or the handler-wide one if it does not belong to a scope
+func (h *fingersCrossedHandler) bufferOf(ctx context.Context) *fingersCrossedBuffer {
+	if ctx == nil {
+		return h.defaultBuffer
+	}
+	scope, ok := ctx.Value(logBufferScopeKey{}).(*logBufferScope)
+	if !ok {
+		return h.defaultBuffer
+	}
+	scope.lock.Lock()
+	defer scope.lock.Unlock()
+	buffer, found := scope.buffers[h]
+	if !found {
+		buffer = newFingersCrossedBuffer()
+		scope.buffers[h] = buffer
+	}
+	return buffer
+}
@@ -0,0 +1,173 @@
+// This file defines the Handler interface - Handlers are the outputs Loggers are writing the log events into
+//
+// Handlers are created from the /handlers section of the config. The 'type' of the handler decides which HandlerFactory is used
+// to create it - if 'type' is omitted then the built-in "zap" type is used. The type specific config goes into the 'settings'
+// section of the handler (except for the "zap" type which keeps its 'outputPaths', 'rollingFile' etc. on the top as it always
+// did). You can plug in your own outputs by implementing the Handler interface and registering a factory for it with
+// RegisterHandlerType() - the built-in types are registered the same way.
+
+package kt_logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// A LogRecord is one log event as Handlers receive it
+type LogRecord struct {
+	Time       time.Time
+	Level      LogLevel
+	LoggerName string
+	// the message - already resolved with the message params
+	Message string
+	// the message before it was resolved with the message params (e.g. "user %v logged in") - so events of the same kind can be
+	// recognized. Events coming from slog or an io.Writer have no params so this is the same as Message.
+	MessageTemplate string
+	// the labels of the log event. Please note: global labels are not part of this, you can get them with GetGlobalLabels()
+	Labels []Label
+	// the context the event was fired with (see Logger.Ctx()) - context.Background() if it was fired without context
+	Context context.Context
+}
+
+// A Handler is an output log events are written into
+type Handler interface {
+	// Writes the log event - the Handler receives only the events passed both the Logger and Handler level filtering
+	Handle(record LogRecord) error
+	// Flushes the buffered log events (if any)
+	Sync() error
+	// Releases the resources (e.g. open files, connections) held by the Handler. It is invoked once the Handler is not used anymore
+	Close() error
+}
+
+// A HandlerFactory creates a Handler from its config - the handlerName is the key of the Handler in the /handlers section
+type HandlerFactory func(handlerName string, cfg HandlerConfigModel) (Handler, error)
+
+// the handler type used if the config does not say anything else
+const _DEFAULT_HANDLER_TYPE string = "zap"
+
+// the registered handler types - the built-in ones register themselves in their files too
+var handlerFactories = map[string]HandlerFactory{}
+var handlerFactoriesLock = new(sync.RWMutex)
+
+// Registers a handler type - so in the config a Handler can use this type with 'type: <typeName>'. Type specific settings go
+// into the 'settings' section of the handler which the factory can decode with HandlerConfigModel.DecodeSettings().
+// Registering a factory with an already used type name replaces the former one.
+func RegisterHandlerType(typeName string, factory HandlerFactory) {
+	handlerFactoriesLock.Lock()
+	defer handlerFactoriesLock.Unlock()
+	handlerFactories[typeName] = factory
+}
+
+// a Handler created from the config - along with the level it is configured on
+type configuredHandler struct {
+	name    string
+	level   LogLevel
+	handler Handler
+}
+
+// creates the Handler from its config using the factory registered for its type
+func newConfiguredHandler(handlerName string, cfg HandlerConfigModel) (*configuredHandler, error) {
+	// handlers without level are on info level - just like they were with zap
+	levelStr := cfg.Level
+	if levelStr == "" {
+		levelStr = "info"
+	}
+	level, err := parseHandlerLevelString(levelStr)
+	if err != nil {
+		return nil, fmt.Errorf("unknown log level '%v' in config at /handlers/%v", cfg.Level, handlerName)
+	}
+	// so the factories see the level the Handler is on
+	cfg.Level = levelStr
+
+	typeName := cfg.Type
+	if typeName == "" {
+		typeName = _DEFAULT_HANDLER_TYPE
+	}
+	handlerFactoriesLock.RLock()
+	factory, contains := handlerFactories[typeName]
+	handlerFactoriesLock.RUnlock()
+	if !contains {
+		return nil, fmt.Errorf("unknown handler type '%v' in config at /handlers/%v", typeName, handlerName)
+	}
+
+	handler, err := factory(handlerName, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create handler in config at /handlers/%v: %w", handlerName, err)
+	}
+	if cfg.FingersCrossed != nil {
+		fingersCrossedHandler, err := newFingersCrossedHandler(handler, *cfg.FingersCrossed)
+		if err != nil {
+			handler.Close()
+			return nil, fmt.Errorf("invalid 'fingersCrossed' in config at /handlers/%v: %w", handlerName, err)
+		}
+		handler = fingersCrossedHandler
+	}
+	if cfg.Sampling != nil {
+		samplingHandler, err := newSamplingHandler(handlerName, handler, *cfg.Sampling)
+		if err != nil {
+			handler.Close()
+			return nil, fmt.Errorf("invalid 'sampling' in config at /handlers/%v: %w", handlerName, err)
+		}
+		handler = samplingHandler
+	}
+	if cfg.Dedup != nil {
+		dedupHandler, err := newDedupHandler(handlerName, handler, *cfg.Dedup)
+		if err != nil {
+			handler.Close()
+			return nil, fmt.Errorf("invalid 'dedup' in config at /handlers/%v: %w", handlerName, err)
+		}
+		handler = dedupHandler
+	}
+	if cfg.Async != nil {
+		asyncHandler, err := newAsyncHandler(handlerName, handler, *cfg.Async)
+		if err != nil {
+			handler.Close()
+			return nil, fmt.Errorf("invalid 'async' in config at /handlers/%v: %w", handlerName, err)
+		}
+		handler = asyncHandler
+	}
+	return &configuredHandler{name: handlerName, level: level, handler: handler}, nil
+}
+
+// Decodes the 'settings' section of the handler config into the given target (pointer to a struct or map) - the target is
+// filled the same way as encoding/json would do it, so use `json:"..."` tags on your struct
+func (cfg HandlerConfigModel) DecodeSettings(target any) error {
+	// settings parsed from yaml may contain map[interface{}]interface{} entries which JSON does not support
+	content, err := json.Marshal(toJsonCompatible(cfg.Settings))
+	if err != nil {
+		return fmt.Errorf("failed to decode handler settings: %v", err)
+	}
+	if err := json.Unmarshal(content, target); err != nil {
+		return fmt.Errorf("failed to decode handler settings: %v", err)
+	}
+	return nil
+}
+
+// converts the generic maps coming from yaml parsing into map[string]any recursively
+func toJsonCompatible(value any) any {
+	switch typedValue := value.(type) {
+	case map[any]any:
+		result := make(map[string]any, len(typedValue))
+		for key, item := range typedValue {
+			result[fmt.Sprint(key)] = toJsonCompatible(item)
+		}
+		return result
+	case map[string]any:
+		result := make(map[string]any, len(typedValue))
+		for key, item := range typedValue {
+			result[key] = toJsonCompatible(item)
+		}
+		return result
+	case []any:
+		result := make([]any, len(typedValue))
+		for idx, item := range typedValue {
+			result[idx] = toJsonCompatible(item)
+		}
+		return result
+	default:
+		return value
+	}
+}
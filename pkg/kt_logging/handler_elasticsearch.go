@@ -0,0 +1,206 @@
+// This file contains the built-in "elasticsearch" Handler - indexing batches of log events with the _bulk API of Elasticsearch
+// or OpenSearch
+//
+// The bulk API reports the outcome per document. Documents rejected temporarily (429 or 5xx) are retried - the already indexed
+// ones are not sent again. Documents rejected for good (e.g. mapping conflicts) are reported on stderr and dropped.
+
+package kt_logging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	_DEFAULT_ELASTICSEARCH_INDEX string = "logs-%Y.%m.%d"
+)
+
+type elasticsearchHandler struct {
+	sender       *httpSender
+	batcher      *batcher[*elasticsearchItem]
+	indexPattern string
+	action       string
+	encoder      zapcore.Encoder
+}
+
+// one log event in a batch
+type elasticsearchItem struct {
+	index    string
+	document []byte
+	// TRUE once Elasticsearch accepted (or rejected for good) the document - so it is not sent again on retry
+	done bool
+}
+
+// the parts of the _bulk response we are interested in
+type elasticsearchBulkResponse struct {
+	Errors bool `json:"errors"`
+	// one entry per document - the key is the action
+	Items []map[string]struct {
+		Status int `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+func init() {
+	RegisterHandlerType("elasticsearch", newElasticsearchHandler)
+}
+
+// the HandlerFactory of the "elasticsearch" handler type
+func newElasticsearchHandler(handlerName string, cfg HandlerConfigModel) (Handler, error) {
+	model := ElasticsearchModel{}
+	if err := cfg.DecodeSettings(&model); err != nil {
+		return nil, err
+	}
+	if model.Url == "" {
+		return nil, fmt.Errorf("elasticsearch 'url' is mandatory")
+	}
+
+	handler := &elasticsearchHandler{indexPattern: model.Index, action: strings.ToLower(model.Action)}
+	if handler.indexPattern == "" {
+		handler.indexPattern = _DEFAULT_ELASTICSEARCH_INDEX
+	}
+	if _, err := expandDatePlaceholders(handler.indexPattern, time.Now()); err != nil {
+		return nil, fmt.Errorf("invalid index '%v': %v", model.Index, err)
+	}
+	switch handler.action {
+	case "":
+		handler.action = "index"
+	case "index", "create":
+	default:
+		return nil, fmt.Errorf("unknown bulk action '%v' - 'index' or 'create' is supported", model.Action)
+	}
+
+	headers := map[string]string{}
+	for name, value := range model.Headers {
+		headers[name] = value
+	}
+	switch {
+	case model.ApiKey != "":
+		headers["Authorization"] = "ApiKey " + model.ApiKey
+	case model.Username != "":
+		headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(model.Username+":"+model.Password))
+	}
+	sender, err := newHttpSender(strings.TrimSuffix(model.Url, "/")+"/_bulk", "", headers, model.Gzip, model.TimeoutSec)
+	if err != nil {
+		return nil, err
+	}
+	handler.sender = sender
+
+	// @timestamp is the convention of the Elastic stack - and mandatory in data streams
+	encoderConfig := newZapEncoderConfig()
+	encoderConfig.TimeKey = "@timestamp"
+	handler.encoder = zapcore.NewJSONEncoder(encoderConfig)
+
+	handler.batcher, err = newBatcher("elasticsearch "+model.Url, model.BatchingModel, handler.send)
+	if err != nil {
+		return nil, err
+	}
+	return handler, nil
+}
+
+func (h *elasticsearchHandler) Handle(record LogRecord) error {
+	buffer, err := h.encoder.EncodeEntry(toZapEntry(record), toZapFields(record))
+	if err != nil {
+		return err
+	}
+	// the encoder terminates the line - exactly what the bulk format needs
+	document := append([]byte{}, buffer.Bytes()...)
+	buffer.Free()
+
+	// we validated the pattern already
+	index, _ := expandDatePlaceholders(h.indexPattern, record.Time.UTC())
+	item := &elasticsearchItem{index: index, document: document}
+	// +the action line roughly
+	return h.batcher.add(item, len(document)+len(index)+32)
+}
+
+func (h *elasticsearchHandler) Sync() error {
+	return h.batcher.sync()
+}
+
+func (h *elasticsearchHandler) Close() error {
+	return h.batcher.close()
+}
+
+func (h *elasticsearchHandler) send(batch []*elasticsearchItem) error {
+	pending := make([]*elasticsearchItem, 0, len(batch))
+	body := bytes.Buffer{}
+	for _, item := range batch {
+		if item.done {
+			continue
+		}
+		pending = append(pending, item)
+		fmt.Fprintf(&body, "{%q:{\"_index\":%q}}\n", h.action, item.index)
+		body.Write(item.document)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	responseBody, err := h.sender.send("application/x-ndjson", body.Bytes())
+	if err != nil {
+		return err
+	}
+	response := elasticsearchBulkResponse{}
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		return permanentError{fmt.Errorf("failed to parse the _bulk response: %v", err)}
+	}
+	if !response.Errors {
+		for _, item := range pending {
+			item.done = true
+		}
+		return nil
+	}
+	if len(response.Items) != len(pending) {
+		return permanentError{fmt.Errorf("the _bulk response contains %d items instead of %d", len(response.Items), len(pending))}
+	}
+
+	retryable := 0
+	// error description -> number of documents
+	rejections := map[string]int{}
+	for idx, item := range response.Items {
+		for _, result := range item {
+			switch {
+			case result.Status >= 200 && result.Status < 300:
+				pending[idx].done = true
+			case result.Status == 429 || result.Status >= 500:
+				retryable++
+			default:
+				pending[idx].done = true
+				description := fmt.Sprintf("status %d", result.Status)
+				if result.Error != nil {
+					description = fmt.Sprintf("%v: %v", result.Error.Type, result.Error.Reason)
+				}
+				rejections[description]++
+			}
+		}
+	}
+	h.reportRejections(rejections)
+	if retryable > 0 {
+		return fmt.Errorf("%d of %d log events were rejected temporarily", retryable, len(pending))
+	}
+	return nil
+}
+
+// writes the documents rejected for good on stderr - grouped by error
+func (h *elasticsearchHandler) reportRejections(rejections map[string]int) {
+	descriptions := make([]string, 0, len(rejections))
+	for description := range rejections {
+		descriptions = append(descriptions, description)
+	}
+	sort.Strings(descriptions)
+	for _, description := range descriptions {
+		fmt.Fprintf(os.Stderr, "kt_logging: elasticsearch rejected %d log events - %v\n", rejections[description], description)
+	}
+}
@@ -0,0 +1,210 @@
+// This file contains the built-in "gelf" Handler - sending log events to Graylog in GELF 1.1 format over UDP (compressed,
+// chunked if needed) or TCP (null byte delimited)
+//
+// The message goes into "short_message", the level is mapped to syslog severity and the logger name, global labels and labels
+// of the event become additional fields - prefixed with '_' as GELF requires.
+
+package kt_logging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	_GELF_COMPRESSION_GZIP string = "gzip"
+	_GELF_COMPRESSION_ZLIB string = "zlib"
+	_GELF_COMPRESSION_NONE string = "none"
+
+	_DEFAULT_GELF_CHUNK_SIZE = 1420
+	// the magic bytes, the message id, the sequence number and the sequence count
+	_GELF_CHUNK_HEADER_SIZE = 12
+	// GELF does not allow more chunks
+	_GELF_MAX_CHUNKS = 128
+)
+
+type gelfHandler struct {
+	conn        *reconnectingConn
+	protocol    string
+	compression string
+	chunkSize   int
+	host        string
+}
+
+func init() {
+	RegisterHandlerType("gelf", newGelfHandler)
+}
+
+// the HandlerFactory of the "gelf" handler type
+func newGelfHandler(handlerName string, cfg HandlerConfigModel) (Handler, error) {
+	model := GelfModel{}
+	if err := cfg.DecodeSettings(&model); err != nil {
+		return nil, err
+	}
+
+	handler := &gelfHandler{
+		protocol:    strings.ToLower(model.Protocol),
+		compression: strings.ToLower(model.Compression),
+		chunkSize:   withDefault(model.ChunkSize, _DEFAULT_GELF_CHUNK_SIZE),
+		host:        model.Host,
+	}
+	if model.Address == "" {
+		return nil, fmt.Errorf("gelf 'address' is mandatory")
+	}
+	switch handler.protocol {
+	case "":
+		handler.protocol = "udp"
+	case "udp", "tcp":
+	default:
+		return nil, fmt.Errorf("unknown gelf protocol '%v' - 'udp' or 'tcp' is supported", model.Protocol)
+	}
+	switch handler.compression {
+	case "":
+		handler.compression = _GELF_COMPRESSION_GZIP
+		if handler.protocol == "tcp" {
+			handler.compression = _GELF_COMPRESSION_NONE
+		}
+	case _GELF_COMPRESSION_GZIP, _GELF_COMPRESSION_ZLIB, _GELF_COMPRESSION_NONE:
+	default:
+		return nil, fmt.Errorf("unknown gelf compression '%v' - 'gzip', 'zlib' or 'none' is supported", model.Compression)
+	}
+	if handler.protocol == "tcp" && handler.compression != _GELF_COMPRESSION_NONE {
+		return nil, fmt.Errorf("gelf over tcp does not support compression")
+	}
+	if handler.chunkSize <= _GELF_CHUNK_HEADER_SIZE {
+		return nil, fmt.Errorf("gelf 'chunkSize' must be bigger than %d", _GELF_CHUNK_HEADER_SIZE)
+	}
+	if handler.host == "" {
+		handler.host, _ = os.Hostname()
+	}
+
+	dial := func(dialer *net.Dialer) (net.Conn, error) { return dialer.Dial(handler.protocol, model.Address) }
+	dialTimeout := time.Duration(withDefault(model.DialTimeoutMs, _DEFAULT_DIAL_TIMEOUT_MS)) * time.Millisecond
+	writeTimeout := time.Duration(withDefault(model.WriteTimeoutMs, _DEFAULT_WRITE_TIMEOUT_MS)) * time.Millisecond
+	handler.conn = newReconnectingConn(dial, dialTimeout, writeTimeout)
+	return handler, nil
+}
+
+func (h *gelfHandler) Handle(record LogRecord) error {
+	message, err := h.formatMessage(record)
+	if err != nil {
+		return err
+	}
+	if h.protocol == "tcp" {
+		return h.conn.write(append(message, 0))
+	}
+
+	message, err = h.compress(message)
+	if err != nil {
+		return err
+	}
+	if len(message) <= h.chunkSize {
+		return h.conn.write(message)
+	}
+	return h.writeChunked(message)
+}
+
+func (h *gelfHandler) Sync() error {
+	// we do not buffer anything
+	return nil
+}
+
+func (h *gelfHandler) Close() error {
+	return h.conn.close()
+}
+
+func (h *gelfHandler) formatMessage(record LogRecord) ([]byte, error) {
+	message := map[string]any{
+		"version":       "1.1",
+		"host":          h.host,
+		"short_message": record.Message,
+		"timestamp":     float64(record.Time.UnixMicro()) / 1e6,
+		"level":         toSyslogSeverity(record.Level),
+		"_logger":       record.LoggerName,
+	}
+	for _, labels := range [][]Label{GetGlobalLabels(), record.Labels} {
+		for _, label := range labels {
+			message[toGelfFieldName(label.key)] = toGelfFieldValue(label)
+		}
+	}
+	return json.Marshal(message)
+}
+
+func (h *gelfHandler) compress(message []byte) ([]byte, error) {
+	if h.compression == _GELF_COMPRESSION_NONE {
+		return message, nil
+	}
+	compressed := bytes.Buffer{}
+	var writer io.WriteCloser
+	if h.compression == _GELF_COMPRESSION_ZLIB {
+		writer = zlib.NewWriter(&compressed)
+	} else {
+		writer = gzip.NewWriter(&compressed)
+	}
+	if _, err := writer.Write(message); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return compressed.Bytes(), nil
+}
+
+// splits the message into GELF chunks - each chunk is sent in its own datagram
+func (h *gelfHandler) writeChunked(message []byte) error {
+	dataSize := h.chunkSize - _GELF_CHUNK_HEADER_SIZE
+	count := (len(message) + dataSize - 1) / dataSize
+	if count > _GELF_MAX_CHUNKS {
+		return fmt.Errorf("gelf message is too big - %d bytes would need %d chunks but max %d is allowed", len(message), count, _GELF_MAX_CHUNKS)
+	}
+	messageID := make([]byte, 8)
+	rand.Read(messageID)
+
+	for seq := 0; seq < count; seq++ {
+		data := message[seq*dataSize : min((seq+1)*dataSize, len(message))]
+		chunk := make([]byte, 0, _GELF_CHUNK_HEADER_SIZE+len(data))
+		chunk = append(chunk, 0x1e, 0x0f)
+		chunk = append(chunk, messageID...)
+		chunk = append(chunk, byte(seq), byte(count))
+		chunk = append(chunk, data...)
+		if err := h.conn.write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// additional field names must match ^[\w\.\-]*$ prefixed with '_' - and "_id" is reserved
+func toGelfFieldName(key string) string {
+	sanitized := strings.Map(func(r rune) rune {
+		if r == '_' || r == '.' || r == '-' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, key)
+	if sanitized == "id" {
+		sanitized = "id_"
+	}
+	return "_" + sanitized
+}
+
+// GELF field values can be strings or numbers only
+func toGelfFieldValue(label Label) any {
+	switch label._type {
+	case IntType:
+		return label.intValue
+	case FloatType:
+		return label.floatValue
+	default:
+		return label.valueAsString()
+	}
+}
@@ -0,0 +1,180 @@
+// This file contains the built-in "http" Handler - shipping batches of JSON encoded log events to an HTTP endpoint as NDJSON
+// (one document per line) or as a JSON array
+//
+// The log events are encoded the same way as the "zap" handler with 'json' encoding does. For batching and retries see
+// batcher.go.
+
+package kt_logging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	_HTTP_FORMAT_NDJSON string = "ndjson"
+	_HTTP_FORMAT_JSON   string = "json"
+
+	_DEFAULT_HTTP_TIMEOUT = 10 * time.Second
+	// we read this much of the response body at most (for error messages and responses we need to process)
+	_HTTP_MAX_RESPONSE_SIZE = 1024 * 1024
+)
+
+type httpHandler struct {
+	format  string
+	sender  *httpSender
+	batcher *batcher[[]byte]
+}
+
+func init() {
+	RegisterHandlerType("http", newHttpHandler)
+}
+
+// the HandlerFactory of the "http" handler type
+func newHttpHandler(handlerName string, cfg HandlerConfigModel) (Handler, error) {
+	model := HttpModel{}
+	if err := cfg.DecodeSettings(&model); err != nil {
+		return nil, err
+	}
+
+	handler := &httpHandler{format: strings.ToLower(model.Format)}
+	switch handler.format {
+	case "":
+		handler.format = _HTTP_FORMAT_NDJSON
+	case _HTTP_FORMAT_NDJSON, _HTTP_FORMAT_JSON:
+	default:
+		return nil, fmt.Errorf("unknown http format '%v' - 'ndjson' or 'json' is supported", model.Format)
+	}
+
+	sender, err := newHttpSender(model.Url, model.Method, model.Headers, model.Gzip, model.TimeoutSec)
+	if err != nil {
+		return nil, err
+	}
+	handler.sender = sender
+
+	handler.batcher, err = newBatcher("http endpoint "+model.Url, model.BatchingModel, handler.send)
+	if err != nil {
+		return nil, err
+	}
+	return handler, nil
+}
+
+func (h *httpHandler) Handle(record LogRecord) error {
+	document, err := encodeRecordAsJson(record)
+	if err != nil {
+		return err
+	}
+	// +1 for the separator
+	return h.batcher.add(document, len(document)+1)
+}
+
+func (h *httpHandler) Sync() error {
+	return h.batcher.sync()
+}
+
+func (h *httpHandler) Close() error {
+	return h.batcher.close()
+}
+
+func (h *httpHandler) send(batch [][]byte) error {
+	body := bytes.Buffer{}
+	contentType := "application/x-ndjson"
+	if h.format == _HTTP_FORMAT_JSON {
+		contentType = "application/json"
+		body.WriteByte('[')
+	}
+	for idx, document := range batch {
+		if idx > 0 && h.format == _HTTP_FORMAT_JSON {
+			body.WriteByte(',')
+		}
+		body.Write(document)
+		if h.format == _HTTP_FORMAT_NDJSON {
+			body.WriteByte('\n')
+		}
+	}
+	if h.format == _HTTP_FORMAT_JSON {
+		body.WriteByte(']')
+	}
+	_, err := h.sender.send(contentType, body.Bytes())
+	return err
+}
+
+// sends request bodies to an HTTP endpoint - shared by the handler types talking HTTP
+type httpSender struct {
+	client  *http.Client
+	url     string
+	method  string
+	headers map[string]string
+	gzip    bool
+}
+
+func newHttpSender(url string, method string, headers map[string]string, gzip bool, timeoutSec int) (*httpSender, error) {
+	if url == "" {
+		return nil, fmt.Errorf("'url' is mandatory")
+	}
+	if method == "" {
+		method = http.MethodPost
+	}
+	// let's validate what we can right now
+	if _, err := http.NewRequest(method, url, nil); err != nil {
+		return nil, fmt.Errorf("invalid url '%v': %v", url, err)
+	}
+	timeout := time.Duration(timeoutSec) * time.Second
+	if timeout <= 0 {
+		timeout = _DEFAULT_HTTP_TIMEOUT
+	}
+	return &httpSender{client: &http.Client{Timeout: timeout}, url: url, method: method, headers: headers, gzip: gzip}, nil
+}
+
+// sends the body and returns the response body. Network errors, 5xx and 429 responses are retryable - other not successful
+// responses are returned as permanentError
+func (s *httpSender) send(contentType string, body []byte) ([]byte, error) {
+	var reader io.Reader = bytes.NewReader(body)
+	if s.gzip {
+		compressed := bytes.Buffer{}
+		gzipWriter := gzip.NewWriter(&compressed)
+		gzipWriter.Write(body)
+		gzipWriter.Close()
+		reader = &compressed
+	}
+
+	request, err := http.NewRequest(s.method, s.url, reader)
+	if err != nil {
+		return nil, permanentError{err}
+	}
+	request.Header.Set("Content-Type", contentType)
+	if s.gzip {
+		request.Header.Set("Content-Encoding", "gzip")
+	}
+	for name, value := range s.headers {
+		request.Header.Set(name, value)
+	}
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	responseBody, err := io.ReadAll(io.LimitReader(response.Body, _HTTP_MAX_RESPONSE_SIZE))
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return responseBody, nil
+	}
+	errorBody := bytes.TrimSpace(responseBody)
+	if len(errorBody) > 512 {
+		errorBody = append(errorBody[:512:512], "..."...)
+	}
+	err = fmt.Errorf("%v responded with %v: %s", s.url, response.Status, errorBody)
+	if response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests {
+		return nil, err
+	}
+	return nil, permanentError{err}
+}
@@ -0,0 +1,121 @@
+// This file contains the built-in "journald" Handler - writing log events into the systemd journal via its native protocol, so
+// they land with real structured fields instead of plain text
+//
+// The level goes into PRIORITY, the message into MESSAGE, the logger name into LOGGER (configurable) and the global labels and
+// labels of the event into upper-cased fields. Entries too big for a datagram are passed to journald in a memfd. The socket
+// handling is platform specific - see journald_socket_*.go
+
+package kt_logging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	_DEFAULT_JOURNALD_SOCKET       string = "/run/systemd/journal/socket"
+	_DEFAULT_JOURNALD_LOGGER_FIELD string = "LOGGER"
+	// journald ignores longer field names
+	_JOURNALD_MAX_FIELD_NAME_LENGTH = 64
+)
+
+type journaldHandler struct {
+	socket      *journalSocket
+	identifier  string
+	loggerField string
+}
+
+func init() {
+	RegisterHandlerType("journald", newJournaldHandler)
+}
+
+// the HandlerFactory of the "journald" handler type
+func newJournaldHandler(handlerName string, cfg HandlerConfigModel) (Handler, error) {
+	model := JournaldModel{}
+	if err := cfg.DecodeSettings(&model); err != nil {
+		return nil, err
+	}
+
+	handler := &journaldHandler{identifier: model.Identifier, loggerField: _DEFAULT_JOURNALD_LOGGER_FIELD}
+	if handler.identifier == "" {
+		handler.identifier = filepath.Base(os.Args[0])
+	}
+	if model.LoggerField != "" {
+		handler.loggerField = toJournalFieldName(model.LoggerField)
+	}
+	socketPath := model.SocketPath
+	if socketPath == "" {
+		socketPath = _DEFAULT_JOURNALD_SOCKET
+	}
+
+	socket, err := newJournalSocket(socketPath)
+	if err != nil {
+		return nil, err
+	}
+	handler.socket = socket
+	return handler, nil
+}
+
+func (h *journaldHandler) Handle(record LogRecord) error {
+	entry := bytes.Buffer{}
+	appendJournalField(&entry, "PRIORITY", strconv.Itoa(toSyslogSeverity(record.Level)))
+	appendJournalField(&entry, "SYSLOG_IDENTIFIER", h.identifier)
+	appendJournalField(&entry, "MESSAGE", record.Message)
+	appendJournalField(&entry, h.loggerField, record.LoggerName)
+	for _, labels := range [][]Label{GetGlobalLabels(), record.Labels} {
+		for _, label := range labels {
+			appendJournalField(&entry, toJournalFieldName(label.key), label.valueAsString())
+		}
+	}
+	return h.socket.send(entry.Bytes())
+}
+
+func (h *journaldHandler) Sync() error {
+	// we do not buffer anything
+	return nil
+}
+
+func (h *journaldHandler) Close() error {
+	return h.socket.close()
+}
+
+// appends the field in the native journal format - values containing newline are written with explicit length
+func appendJournalField(entry *bytes.Buffer, name string, value string) {
+	entry.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		entry.WriteByte('=')
+		entry.WriteString(value)
+		entry.WriteByte('\n')
+		return
+	}
+	entry.WriteByte('\n')
+	binary.Write(entry, binary.LittleEndian, uint64(len(value)))
+	entry.WriteString(value)
+	entry.WriteByte('\n')
+}
+
+// journal field names can contain only A-Z, 0-9 and '_', can not start with '_' (those are the trusted fields) or digit and
+// are limited to 64 characters
+func toJournalFieldName(key string) string {
+	sanitized := strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		return '_'
+	}, key)
+	sanitized = strings.TrimLeft(sanitized, "_")
+	if sanitized == "" || (sanitized[0] >= '0' && sanitized[0] <= '9') {
+		sanitized = "LABEL_" + sanitized
+	}
+	if len(sanitized) > _JOURNALD_MAX_FIELD_NAME_LENGTH {
+		sanitized = sanitized[:_JOURNALD_MAX_FIELD_NAME_LENGTH]
+	}
+	return sanitized
+}
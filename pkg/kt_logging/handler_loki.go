@@ -0,0 +1,252 @@
+// This file contains the built-in "loki" Handler - pushing batches of log events to the Grafana Loki push API
+//
+// The configured label keys (by default the global labels and the level) become stream labels, everything else goes into the
+// log line encoded as JSON. As every different combination of stream label values opens a new stream in Loki the number of
+// streams the handler creates is capped.
+
+package kt_logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	_DEFAULT_LOKI_MAX_STREAMS = 1000
+	// the stream label put on the streams of the log events which did not fit into the max streams
+	_LOKI_OVERFLOW_LABEL string = "overflow"
+)
+
+type lokiHandler struct {
+	sender  *httpSender
+	batcher *batcher[lokiEntry]
+	// nil means the default: the global labels and the level
+	streamLabels map[string]bool
+	maxStreams   int
+	// the encoders of the log line - with and without the level
+	lineEncoder        zapcore.Encoder
+	lineEncoderNoLevel zapcore.Encoder
+
+	lock *sync.Mutex
+	// the streams opened so far
+	streams map[string]bool
+}
+
+// one log event in a batch
+type lokiEntry struct {
+	streamKey string
+	stream    map[string]string
+	time      time.Time
+	line      string
+}
+
+// a label which might become a stream label
+type lokiLabelCandidate struct {
+	label    Label
+	isGlobal bool
+}
+
+// the body of a push request
+type lokiPushRequest struct {
+	Streams []*lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	// [timestamp in unix nanos, log line] pairs
+	Values [][2]string `json:"values"`
+}
+
+func init() {
+	RegisterHandlerType("loki", newLokiHandler)
+}
+
+// the HandlerFactory of the "loki" handler type
+func newLokiHandler(handlerName string, cfg HandlerConfigModel) (Handler, error) {
+	model := LokiModel{}
+	if err := cfg.DecodeSettings(&model); err != nil {
+		return nil, err
+	}
+
+	headers := map[string]string{}
+	for name, value := range model.Headers {
+		headers[name] = value
+	}
+	if model.TenantID != "" {
+		headers["X-Scope-OrgID"] = model.TenantID
+	}
+	sender, err := newHttpSender(model.Url, "", headers, model.Gzip, model.TimeoutSec)
+	if err != nil {
+		return nil, err
+	}
+
+	lineEncoderConfig := newZapEncoderConfig()
+	// the timestamp is part of the entry anyways
+	lineEncoderConfig.TimeKey = ""
+	handler := &lokiHandler{
+		sender:      sender,
+		maxStreams:  withDefault(model.MaxStreams, _DEFAULT_LOKI_MAX_STREAMS),
+		lineEncoder: zapcore.NewJSONEncoder(lineEncoderConfig),
+		lock:        new(sync.Mutex),
+		streams:     map[string]bool{},
+	}
+	lineEncoderConfig.LevelKey = ""
+	handler.lineEncoderNoLevel = zapcore.NewJSONEncoder(lineEncoderConfig)
+	if model.StreamLabels != nil {
+		handler.streamLabels = map[string]bool{}
+		for _, key := range model.StreamLabels {
+			handler.streamLabels[key] = true
+		}
+	}
+
+	handler.batcher, err = newBatcher("loki "+model.Url, model.BatchingModel, handler.send)
+	if err != nil {
+		return nil, err
+	}
+	return handler, nil
+}
+
+func (h *lokiHandler) isStreamLabel(key string, isGlobal bool) bool {
+	if h.streamLabels == nil {
+		return isGlobal || key == "level"
+	}
+	return h.streamLabels[key]
+}
+
+func (h *lokiHandler) Handle(record LogRecord) error {
+	globalLabels := GetGlobalLabels()
+	candidates := make([]lokiLabelCandidate, 0, 1+len(globalLabels)+len(record.Labels))
+	candidates = append(candidates, lokiLabelCandidate{label: StringLabel("logger", record.LoggerName)})
+	for _, label := range globalLabels {
+		candidates = append(candidates, lokiLabelCandidate{label: label, isGlobal: true})
+	}
+	for _, label := range record.Labels {
+		candidates = append(candidates, lokiLabelCandidate{label: label})
+	}
+
+	levelIsStreamLabel := h.isStreamLabel("level", false)
+	stream := map[string]string{}
+	if levelIsStreamLabel {
+		stream["level"] = record.Level.String()
+	}
+	for _, candidate := range candidates {
+		if h.isStreamLabel(candidate.label.key, candidate.isGlobal) {
+			stream[toLokiLabelName(candidate.label.key)] = candidate.label.valueAsString()
+		}
+	}
+	streamKey := toLokiStreamKey(stream)
+
+	overflow := !h.openStream(streamKey)
+	if overflow {
+		// too many streams - only the global labels remain stream labels, the rest goes into the log line
+		stream = map[string]string{_LOKI_OVERFLOW_LABEL: "true"}
+		for _, candidate := range candidates {
+			if candidate.isGlobal && h.isStreamLabel(candidate.label.key, true) {
+				stream[toLokiLabelName(candidate.label.key)] = candidate.label.valueAsString()
+			}
+		}
+		streamKey = toLokiStreamKey(stream)
+		levelIsStreamLabel = false
+	}
+
+	fields := make([]zap.Field, 0, len(candidates))
+	for _, candidate := range candidates {
+		inStream := h.isStreamLabel(candidate.label.key, candidate.isGlobal) && (!overflow || candidate.isGlobal)
+		if !inStream {
+			fields = append(fields, candidate.label.toZapField())
+		}
+	}
+	encoder := h.lineEncoderNoLevel
+	if !levelIsStreamLabel {
+		encoder = h.lineEncoder
+	}
+	buffer, err := encoder.EncodeEntry(toZapEntry(record), fields)
+	if err != nil {
+		return err
+	}
+	line := strings.TrimSuffix(buffer.String(), "\n")
+	buffer.Free()
+
+	entry := lokiEntry{streamKey: streamKey, stream: stream, time: record.Time, line: line}
+	// the timestamp and the quotes and commas around - roughly
+	return h.batcher.add(entry, len(line)+32)
+}
+
+// returns FALSE if the stream is not opened yet and we can not open more streams
+func (h *lokiHandler) openStream(streamKey string) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.streams[streamKey] {
+		return true
+	}
+	if len(h.streams) >= h.maxStreams {
+		return false
+	}
+	h.streams[streamKey] = true
+	return true
+}
+
+func (h *lokiHandler) Sync() error {
+	return h.batcher.sync()
+}
+
+func (h *lokiHandler) Close() error {
+	return h.batcher.close()
+}
+
+func (h *lokiHandler) send(batch []lokiEntry) error {
+	request := lokiPushRequest{}
+	streams := map[string]*lokiStream{}
+	for _, entry := range batch {
+		stream, contains := streams[entry.streamKey]
+		if !contains {
+			stream = &lokiStream{Stream: entry.stream}
+			streams[entry.streamKey] = stream
+			request.Streams = append(request.Streams, stream)
+		}
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(entry.time.UnixNano(), 10), entry.line})
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return permanentError{err}
+	}
+	_, err = h.sender.send("application/json", body)
+	return err
+}
+
+// Loki label names must match [a-zA-Z_][a-zA-Z0-9_]*
+func toLokiLabelName(key string) string {
+	sanitized := []byte(key)
+	for idx, c := range sanitized {
+		if !(c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (idx > 0 && c >= '0' && c <= '9')) {
+			sanitized[idx] = '_'
+		}
+	}
+	if len(sanitized) == 0 {
+		return "_"
+	}
+	return string(sanitized)
+}
+
+// a string identifying the stream - built from the sorted label names and values
+func toLokiStreamKey(stream map[string]string) string {
+	names := make([]string, 0, len(stream))
+	for name := range stream {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	key := bytes.Buffer{}
+	for _, name := range names {
+		fmt.Fprintf(&key, "%s=%q,", name, stream[name])
+	}
+	return key.String()
+}
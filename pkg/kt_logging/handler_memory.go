@@ -0,0 +1,235 @@
+// This file contains the built-in "memory" Handler - keeping the most recent log events in a ring buffer so they can be inspected
+// in runtime with RecentEvents() or over HTTP with RecentEventsHandler() - even if the other outputs are not accessible
+//
+// The buffer is limited by the number of events and/or their (approximate) size in bytes - the oldest events are evicted first.
+
+package kt_logging
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	_DEFAULT_MEMORY_MAX_EVENTS = 1000
+	// roughly what a record takes in memory besides its strings
+	_MEMORY_RECORD_OVERHEAD = 64
+)
+
+type memoryHandler struct {
+	maxEvents int
+	maxBytes  int
+	lock      *sync.Mutex
+	// the buffered events are records[start:] - the evicted ones are cut off from time to time
+	records []LogRecord
+	sizes   []int
+	start   int
+	bytes   int
+}
+
+// Filters the events returned by RecentEvents() - the zero value does not filter anything
+type RecentEventsFilter struct {
+	// only events on this level or more severe - NoneLevel (the zero value) means all levels
+	Level LogLevel
+	// only events of this Logger and its child Loggers
+	LoggerName string
+	// only events logged at or after this time
+	Since time.Time
+	// only events with a message containing this
+	Contains string
+	// only the last N events matching the filter - 0 means all
+	Limit int
+}
+
+func init() {
+	RegisterHandlerType("memory", newMemoryHandler)
+}
+
+// the HandlerFactory of the "memory" handler type
+func newMemoryHandler(handlerName string, cfg HandlerConfigModel) (Handler, error) {
+	model := MemoryModel{}
+	if err := cfg.DecodeSettings(&model); err != nil {
+		return nil, err
+	}
+	if model.MaxEvents < 0 || model.MaxBytes < 0 {
+		return nil, fmt.Errorf("'maxEvents' and 'maxBytes' can not be negative")
+	}
+	if model.MaxEvents == 0 && model.MaxBytes == 0 {
+		model.MaxEvents = _DEFAULT_MEMORY_MAX_EVENTS
+	}
+	return &memoryHandler{maxEvents: model.MaxEvents, maxBytes: model.MaxBytes, lock: new(sync.Mutex)}, nil
+}
+
+func (h *memoryHandler) Handle(record LogRecord) error {
+	// we do not want to keep alive what the context references
+	record.Context = context.Background()
+	size := memoryRecordSize(record)
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.records = append(h.records, record)
+	h.sizes = append(h.sizes, size)
+	h.bytes += size
+	for h.isOverLimit() {
+		h.records[h.start] = LogRecord{}
+		h.bytes -= h.sizes[h.start]
+		h.start++
+	}
+	if h.start > len(h.records)/2 {
+		// time to release the evicted ones
+		h.records = append([]LogRecord{}, h.records[h.start:]...)
+		h.sizes = append([]int{}, h.sizes[h.start:]...)
+		h.start = 0
+	}
+	return nil
+}
+
+func (h *memoryHandler) Sync() error {
+	return nil
+}
+
+func (h *memoryHandler) Close() error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.records = nil
+	h.sizes = nil
+	h.start = 0
+	h.bytes = 0
+	return nil
+}
+
+// NOT THREAD SAFE! Already assumes Lock is established
+func (h *memoryHandler) isOverLimit() bool {
+	count := len(h.records) - h.start
+	if count <= 1 {
+		// we keep the last one even if it is bigger than the limit
+		return false
+	}
+	return (h.maxEvents > 0 && count > h.maxEvents) || (h.maxBytes > 0 && h.bytes > h.maxBytes)
+}
+
+// returns the buffered events matching the filter - oldest first
+func (h *memoryHandler) recentEvents(filter RecentEventsFilter) []LogRecord {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	matching := []LogRecord{}
+	// we go backwards so we can stop at the limit
+	for idx := len(h.records) - 1; idx >= h.start; idx-- {
+		if filter.Limit > 0 && len(matching) >= filter.Limit {
+			break
+		}
+		if filter.matches(h.records[idx]) {
+			matching = append(matching, h.records[idx])
+		}
+	}
+	for i, j := 0, len(matching)-1; i < j; i, j = i+1, j-1 {
+		matching[i], matching[j] = matching[j], matching[i]
+	}
+	return matching
+}
+
+func (filter RecentEventsFilter) matches(record LogRecord) bool {
+	if filter.Level != NoneLevel && record.Level.filterLevel() > filter.Level.filterLevel() {
+		return false
+	}
+	if filter.LoggerName != "" && record.LoggerName != filter.LoggerName && !strings.HasPrefix(record.LoggerName, filter.LoggerName+".") {
+		return false
+	}
+	if !filter.Since.IsZero() && record.Time.Before(filter.Since) {
+		return false
+	}
+	return filter.Contains == "" || strings.Contains(record.Message, filter.Contains)
+}
+
+// the approximate memory the record takes
+func memoryRecordSize(record LogRecord) int {
+	size := _MEMORY_RECORD_OVERHEAD + len(record.LoggerName) + len(record.Message)
+	for _, label := range record.Labels {
+		size += len(label.key) + len(label.valueAsString())
+	}
+	return size
+}
+
+// Returns the log events kept by the "memory" handler with the given name which match the filter - oldest first. Returns error
+// if there is no such handler or it is not a "memory" handler.
+func RecentEvents(handlerName string, filter RecentEventsFilter) ([]LogRecord, error) {
+	loggersLock.RLock()
+	configured, found := activeHandlers.handlers[handlerName]
+	loggersLock.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("handler '%v' does not exist", handlerName)
+	}
+	memory, isMemory := unwrapHandler(configured.handler).(*memoryHandler)
+	if !isMemory {
+		return nil, fmt.Errorf("handler '%v' is not a \"memory\" handler", handlerName)
+	}
+	return memory.recentEvents(filter), nil
+}
+
+// Returns an http.Handler which dumps the log events kept by the "memory" handler with the given name as NDJSON - encoded the same
+// way as the "zap" handler with 'json' encoding does. The events can be filtered with query parameters (see RecentEventsFilter):
+//
+//	level     - e.g. "warning" - this level or more severe
+//	logger    - the Logger and its child Loggers
+//	since     - RFC 3339 time or a Go duration (e.g. "5m" - the last 5 minutes)
+//	contains  - the message contains this
+//	limit     - the last N events
+func RecentEventsHandler(handlerName string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		filter, err := parseRecentEventsFilter(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		records, err := RecentEvents(handlerName, filter)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for _, record := range records {
+			line, err := encodeRecordAsJson(record)
+			if err != nil {
+				continue
+			}
+			w.Write(append(line, '\n'))
+		}
+	})
+}
+
+func parseRecentEventsFilter(r *http.Request) (RecentEventsFilter, error) {
+	query := r.URL.Query()
+	filter := RecentEventsFilter{LoggerName: query.Get("logger"), Contains: query.Get("contains")}
+	if levelStr := query.Get("level"); levelStr != "" {
+		level, err := parseLogLevelString(levelStr)
+		if err != nil {
+			return filter, err
+		}
+		filter.Level = level
+	}
+	if since := query.Get("since"); since != "" {
+		if duration, err := time.ParseDuration(since); err == nil {
+			filter.Since = time.Now().Add(-duration)
+		} else if filter.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			return filter, fmt.Errorf("invalid 'since' value '%v' - RFC 3339 time or duration is expected", since)
+		}
+	}
+	if limit := query.Get("limit"); limit != "" {
+		var err error
+		if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit < 0 {
+			return filter, fmt.Errorf("invalid 'limit' value '%v'", limit)
+		}
+	}
+	return filter, nil
+}
@@ -0,0 +1,82 @@
+package kt_logging
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"syscall"
+)
+
+// the Handlers created from the config
+type handlerSet struct {
+	handlers map[string]*configuredHandler
+	// nil if the set is empty
+	generation *handlerGeneration
+}
+
+// guards the Handlers of a handlerSet against being closed while log events are still being dispatched to them (e.g. during a
+// config reload)
+type handlerGeneration struct {
+	// dispatching holds it for reading, retiring for writing
+	lock sync.RWMutex
+	// TRUE once the Handlers were replaced - nothing should be dispatched to them anymore
+	retired bool
+}
+
+func newHandlerSet() handlerSet {
+	return handlerSet{handlers: map[string]*configuredHandler{}, generation: &handlerGeneration{}}
+}
+
+// marks the handlers as not used anymore - returns once the log events being dispatched to them are done. After this the
+// handlers can be closed.
+func (hs handlerSet) retire() {
+	if hs.generation == nil {
+		return
+	}
+	hs.generation.lock.Lock()
+	hs.generation.retired = true
+	hs.generation.lock.Unlock()
+}
+
+// flushes all the handlers - returns the collected errors
+func (hs handlerSet) sync() error {
+	names := make([]string, 0, len(hs.handlers))
+	for name := range hs.handlers {
+		names = append(names, name)
+	}
+	// so the errors are coming in a predictable order
+	sort.Strings(names)
+
+	errs := []error{}
+	for _, name := range names {
+		if err := hs.handlers[name].handler.Sync(); err != nil && !isIgnorableSyncError(err) {
+			errs = append(errs, fmt.Errorf("failed to sync handler '%v': %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// releases all the resources the handlers hold - returns the collected errors
+func (hs handlerSet) close() error {
+	errs := []error{}
+	for name, handler := range hs.handlers {
+		if err := handler.handler.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close handler '%v': %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// syncing stdout / stderr fails if they are attached to a terminal or pipe - this is not a real problem so we do not report it
+func isIgnorableSyncError(err error) bool {
+	return errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOTTY)
+}
+
+// adapter so simple close functions can be used as io.Closer
+type closerFunc func()
+
+func (f closerFunc) Close() error {
+	f()
+	return nil
+}
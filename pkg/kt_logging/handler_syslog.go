@@ -0,0 +1,249 @@
+// This file contains the built-in "syslog" Handler - sending log events to a syslog server in RFC 5424 or RFC 3164 format over
+// unix socket, UDP, TCP or TLS
+//
+// In RFC 5424 format the logger name, global labels and labels of the event are rendered as structured data. In RFC 3164 format
+// (which has no structured data) they are appended to the message as key="value" pairs.
+
+package kt_logging
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	_SYSLOG_FORMAT_RFC5424 string = "rfc5424"
+	_SYSLOG_FORMAT_RFC3164 string = "rfc3164"
+
+	_FRAMING_OCTET_COUNTING string = "octet-counting"
+	_FRAMING_NEWLINE        string = "newline"
+
+	_DEFAULT_SYSLOG_SD_ID string = "labels@32473"
+)
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10,
+	"ftp": 11, "local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// the usual places of the local syslog socket
+var localSyslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+type syslogHandler struct {
+	conn     *reconnectingConn
+	facility int
+	appName  string
+	hostname string
+	format   string
+	// empty if the network is not a stream
+	framing string
+	sdID    string
+	pid     int
+}
+
+func init() {
+	RegisterHandlerType("syslog", newSyslogHandler)
+}
+
+// the HandlerFactory of the "syslog" handler type
+func newSyslogHandler(handlerName string, cfg HandlerConfigModel) (Handler, error) {
+	model := SyslogModel{}
+	if err := cfg.DecodeSettings(&model); err != nil {
+		return nil, err
+	}
+
+	handler := &syslogHandler{
+		appName:  model.AppName,
+		hostname: model.Hostname,
+		format:   strings.ToLower(model.Format),
+		framing:  strings.ToLower(model.Framing),
+		sdID:     model.StructuredDataID,
+		pid:      os.Getpid(),
+	}
+
+	facilityName := strings.ToLower(model.Facility)
+	if facilityName == "" {
+		facilityName = "user"
+	}
+	facility, contains := syslogFacilities[facilityName]
+	if !contains {
+		return nil, fmt.Errorf("unknown syslog facility '%v'", model.Facility)
+	}
+	handler.facility = facility
+
+	switch handler.format {
+	case "":
+		handler.format = _SYSLOG_FORMAT_RFC5424
+	case _SYSLOG_FORMAT_RFC5424, _SYSLOG_FORMAT_RFC3164:
+	default:
+		return nil, fmt.Errorf("unknown syslog format '%v' - 'rfc5424' or 'rfc3164' is supported", model.Format)
+	}
+
+	if handler.appName == "" {
+		handler.appName = filepath.Base(os.Args[0])
+	}
+	if handler.hostname == "" {
+		handler.hostname, _ = os.Hostname()
+	}
+	if handler.sdID == "" {
+		handler.sdID = _DEFAULT_SYSLOG_SD_ID
+	}
+
+	network := strings.ToLower(model.Network)
+	var dial func(dialer *net.Dialer) (net.Conn, error)
+	switch network {
+	case "":
+		if model.Address != "" {
+			return nil, fmt.Errorf("'network' is mandatory if 'address' is given")
+		}
+		dial = dialLocalSyslog
+	case "unixgram", "udp":
+		dial = func(dialer *net.Dialer) (net.Conn, error) { return dialer.Dial(network, model.Address) }
+	case "unix", "tcp":
+		dial = func(dialer *net.Dialer) (net.Conn, error) { return dialer.Dial(network, model.Address) }
+		handler.framing = withDefaultFraming(handler.framing)
+	case "tls":
+		dial = func(dialer *net.Dialer) (net.Conn, error) {
+			return tls.DialWithDialer(dialer, "tcp", model.Address, &tls.Config{})
+		}
+		handler.framing = withDefaultFraming(handler.framing)
+	default:
+		return nil, fmt.Errorf("unknown syslog network '%v'", model.Network)
+	}
+	if handler.framing != "" && handler.framing != _FRAMING_OCTET_COUNTING && handler.framing != _FRAMING_NEWLINE {
+		return nil, fmt.Errorf("unknown framing '%v' - 'octet-counting' or 'newline' is supported", model.Framing)
+	}
+	dialTimeout := time.Duration(withDefault(model.DialTimeoutMs, _DEFAULT_DIAL_TIMEOUT_MS)) * time.Millisecond
+	writeTimeout := time.Duration(withDefault(model.WriteTimeoutMs, _DEFAULT_WRITE_TIMEOUT_MS)) * time.Millisecond
+	handler.conn = newReconnectingConn(dial, dialTimeout, writeTimeout)
+
+	return handler, nil
+}
+
+func withDefaultFraming(framing string) string {
+	if framing == "" {
+		return _FRAMING_OCTET_COUNTING
+	}
+	return framing
+}
+
+// connects to the first local syslog socket which works
+func dialLocalSyslog(dialer *net.Dialer) (net.Conn, error) {
+	var lastErr error
+	for _, socketPath := range localSyslogSockets {
+		conn, err := dialer.Dial("unixgram", socketPath)
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("could not connect to local syslog: %v", lastErr)
+}
+
+func (h *syslogHandler) Handle(record LogRecord) error {
+	message := h.formatMessage(record)
+	switch h.framing {
+	case _FRAMING_OCTET_COUNTING:
+		message = fmt.Sprintf("%d %s", len(message), message)
+	case _FRAMING_NEWLINE:
+		message = message + "\n"
+	}
+	return h.conn.write([]byte(message))
+}
+
+func (h *syslogHandler) Sync() error {
+	// we do not buffer anything
+	return nil
+}
+
+func (h *syslogHandler) Close() error {
+	return h.conn.close()
+}
+
+func (h *syslogHandler) formatMessage(record LogRecord) string {
+	priority := h.facility*8 + toSyslogSeverity(record.Level)
+	globalLabels := GetGlobalLabels()
+	labels := make([]Label, 0, 1+len(globalLabels)+len(record.Labels))
+	labels = append(labels, StringLabel("logger", record.LoggerName))
+	labels = append(labels, globalLabels...)
+	labels = append(labels, record.Labels...)
+
+	if h.format == _SYSLOG_FORMAT_RFC3164 {
+		builder := strings.Builder{}
+		fmt.Fprintf(&builder, "<%d>%s %s %s[%d]: %s", priority, record.Time.Format("Jan _2 15:04:05"), h.hostname, h.appName, h.pid, record.Message)
+		for _, label := range labels {
+			fmt.Fprintf(&builder, " %s=%q", label.key, label.valueAsString())
+		}
+		return builder.String()
+	}
+
+	builder := strings.Builder{}
+	fmt.Fprintf(&builder, "<%d>1 %s %s %s %d - [%s", priority, record.Time.Format("2006-01-02T15:04:05.000000Z07:00"),
+		toSyslogHeaderField(h.hostname, 255), toSyslogHeaderField(h.appName, 48), h.pid, h.sdID)
+	for _, label := range labels {
+		fmt.Fprintf(&builder, " %s=\"%s\"", toSyslogParamName(label.key), escapeSyslogParamValue(label.valueAsString()))
+	}
+	builder.WriteString("] ")
+	builder.WriteString(record.Message)
+	return builder.String()
+}
+
+// maps our levels to syslog severities
+func toSyslogSeverity(level LogLevel) int {
+	switch level {
+	case FatalLevel, PanicLevel:
+		return 2 // critical
+	case ErrorLevel:
+		return 3 // error
+	case WarningLevel:
+		return 4 // warning
+	case InfoLevel:
+		return 6 // informational
+	default:
+		return 7 // debug
+	}
+}
+
+// header fields must be non-empty printable US-ASCII without spaces with limited length
+func toSyslogHeaderField(value string, maxLen int) string {
+	sanitized := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, value)
+	if sanitized == "" {
+		return "-"
+	}
+	if len(sanitized) > maxLen {
+		sanitized = sanitized[:maxLen]
+	}
+	return sanitized
+}
+
+// structured data param names can not contain '=', ' ', ']', '"' and are limited to 32 characters
+func toSyslogParamName(key string) string {
+	sanitized := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, key)
+	if sanitized == "" {
+		return "_"
+	}
+	if len(sanitized) > 32 {
+		sanitized = sanitized[:32]
+	}
+	return sanitized
+}
+
+// in structured data param values '"', '\' and ']' must be escaped
+func escapeSyslogParamValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
@@ -0,0 +1,218 @@
+// This file contains the built-in "zap" Handler - writing log events with go.uber.org/zap into STDOUT / STDERR, files, rolling
+// files or a TCP / UDP endpoint in 'json' or 'console' encoding
+
+package kt_logging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// zap does not have trace level - we use a custom level below debug for that
+const _ZAP_TRACE_LEVEL = zapcore.DebugLevel - 1
+
+// The built-in Handler writing log events with a zap Logger
+type ZapHandler struct {
+	zapLogger *zap.Logger
+	// resources (e.g. opened files) we need to close at the end
+	closers []io.Closer
+}
+
+// Creates a Handler writing log events into the given zap Logger - you can use this from your own HandlerFactory if you want to
+// build the zap Logger yourself. The closers are invoked when the Handler is closed.
+func NewZapHandler(zapLogger *zap.Logger, closers ...io.Closer) *ZapHandler {
+	return &ZapHandler{zapLogger: zapLogger, closers: closers}
+}
+
+// Returns the underlying zap Logger
+func (h *ZapHandler) GetZapLogger() *zap.Logger {
+	return h.zapLogger
+}
+
+func (h *ZapHandler) Handle(record LogRecord) error {
+	// note: we go via the Core directly - so zap does not panic / exit on its own on Panic and Fatal levels, we take care of that
+	if checkedEntry := h.zapLogger.Core().Check(toZapEntry(record), nil); checkedEntry != nil {
+		checkedEntry.Write(toZapFields(record)...)
+	}
+	return nil
+}
+
+func (h *ZapHandler) Sync() error {
+	return h.zapLogger.Sync()
+}
+
+func (h *ZapHandler) Close() error {
+	errs := []error{}
+	for _, closer := range h.closers {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Rotates the rolling file of the Handler (see RotateFiles()) - if it writes one
+func (h *ZapHandler) Rotate() error {
+	errs := []error{}
+	for _, closer := range h.closers {
+		if rotator, isRotator := closer.(Rotator); isRotator {
+			if err := rotator.Rotate(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func init() {
+	RegisterHandlerType(_DEFAULT_HANDLER_TYPE, newZapHandler)
+}
+
+// the HandlerFactory of the "zap" handler type
+func newZapHandler(handlerName string, cfg HandlerConfigModel) (Handler, error) {
+	level, err := parseHandlerLevelString(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	zapLevel, _ := toZapLevel(level)
+
+	encoder, err := newZapEncoder(cfg.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := 0
+	for _, configured := range []bool{len(cfg.OutputPaths) > 0, cfg.RollingFile != nil, cfg.Network != nil} {
+		if configured {
+			outputs++
+		}
+	}
+	if outputs > 1 {
+		// this is not allowed!
+		return nil, fmt.Errorf("only one of 'outputPaths', 'rollingFile' and 'network' can be used on a handler")
+	}
+
+	var writer zapcore.WriteSyncer
+	var closer io.Closer
+	switch {
+	case cfg.RollingFile != nil:
+		rollingWriter, err := newRollingFileWriter(handlerName, *cfg.RollingFile)
+		if err != nil {
+			return nil, err
+		}
+		writer = rollingWriter
+		closer = rollingWriter
+	case cfg.Network != nil:
+		networkWriter, err := newNetworkWriter(*cfg.Network)
+		if err != nil {
+			return nil, err
+		}
+		writer = networkWriter
+		closer = networkWriter
+	default:
+		sink, closeSink, err := zap.Open(cfg.OutputPaths...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open outputs: %v", err)
+		}
+		writer = sink
+		closer = closerFunc(closeSink)
+	}
+
+	core := zapcore.NewCore(encoder, writer, zapLevel)
+	return NewZapHandler(zap.New(core), closer), nil
+}
+
+func toZapEntry(record LogRecord) zapcore.Entry {
+	zapLevel, _ := toZapLevel(record.Level)
+	return zapcore.Entry{Level: zapLevel, Time: record.Time, Message: record.Message}
+}
+
+// the fields of the log event: logger name, global labels and the labels of the event
+func toZapFields(record LogRecord) []zap.Field {
+	// we add the name of the logger
+	zapGlobalLabels := getZapGlobalLabels()
+	fields := make([]zap.Field, 0, 1+len(zapGlobalLabels)+len(record.Labels))
+	fields = append(fields, zap.String("logger", record.LoggerName))
+	// and context variables - if exists
+	fields = append(fields, zapGlobalLabels...)
+	for _, label := range record.Labels {
+		fields = append(fields, label.toZapField())
+	}
+	return fields
+}
+
+// encodes the log event into a JSON line exactly the same way as the "zap" handler with 'json' encoding does - for handlers
+// shipping JSON documents
+func encodeRecordAsJson(record LogRecord) ([]byte, error) {
+	buffer, err := jsonRecordEncoder.EncodeEntry(toZapEntry(record), toZapFields(record))
+	if err != nil {
+		return nil, err
+	}
+	defer buffer.Free()
+	// the encoder terminates the line - we do not need that
+	return bytes.TrimSuffix(append([]byte{}, buffer.Bytes()...), []byte{'\n'}), nil
+}
+
+var jsonRecordEncoder = zapcore.NewJSONEncoder(newZapEncoderConfig())
+
+// the encoder config we use in all zap based outputs
+func newZapEncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		MessageKey:  "message",
+		LevelKey:    "level",
+		TimeKey:     "time",
+		EncodeLevel: encodeZapLevel,
+		EncodeTime:  zapcore.RFC3339NanoTimeEncoder,
+	}
+}
+
+// creates the encoder for the 'encoding' given in the handler config
+func newZapEncoder(encoding string) (zapcore.Encoder, error) {
+	switch encoding {
+	case "console":
+		return zapcore.NewConsoleEncoder(newZapEncoderConfig()), nil
+	case "json", "":
+		return zapcore.NewJSONEncoder(newZapEncoderConfig()), nil
+	default:
+		return nil, fmt.Errorf("unknown encoding '%v'", encoding)
+	}
+}
+
+// maps our levels to zap levels - returns FALSE if the level is unknown
+func toZapLevel(level LogLevel) (zapcore.Level, bool) {
+	switch level {
+	case NoneLevel:
+		// nothing is enabled on this level
+		return zapcore.InvalidLevel, true
+	case ErrorLevel:
+		return zapcore.ErrorLevel, true
+	case WarningLevel:
+		return zapcore.WarnLevel, true
+	case InfoLevel:
+		return zapcore.InfoLevel, true
+	case DebugLevel:
+		return zapcore.DebugLevel, true
+	case TraceLevel:
+		return _ZAP_TRACE_LEVEL, true
+	case PanicLevel:
+		return zapcore.PanicLevel, true
+	case FatalLevel:
+		return zapcore.FatalLevel, true
+	default:
+		return zapcore.InvalidLevel, false
+	}
+}
+
+// encodes the levels lowercase - including our custom trace level
+func encodeZapLevel(level zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	if level == _ZAP_TRACE_LEVEL {
+		enc.AppendString("trace")
+		return
+	}
+	zapcore.LowercaseLevelEncoder(level, enc)
+}
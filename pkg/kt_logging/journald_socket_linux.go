@@ -0,0 +1,101 @@
+//go:build linux
+
+package kt_logging
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// the native socket of journald - entries are sent in datagrams, or in a sealed memfd if they are too big for a datagram
+type journalSocket struct {
+	address *net.UnixAddr
+	lock    sync.Mutex
+	conn    *net.UnixConn
+}
+
+func newJournalSocket(socketPath string) (*journalSocket, error) {
+	if _, err := os.Stat(socketPath); err != nil {
+		return nil, fmt.Errorf("journald socket is not available: %v", err)
+	}
+	return &journalSocket{address: &net.UnixAddr{Name: socketPath, Net: "unixgram"}}, nil
+}
+
+func (s *journalSocket) send(entry []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// if we had a connection then the failure might be because journald was restarted meanwhile so we retry with a new one
+	retry := s.conn != nil
+	err := s.sendOnce(entry)
+	if err != nil && retry {
+		err = s.sendOnce(entry)
+	}
+	return err
+}
+
+// NOT THREAD SAFE! Already assumes Lock is established.
+func (s *journalSocket) sendOnce(entry []byte) error {
+	if s.conn == nil {
+		conn, err := net.DialUnix("unixgram", nil, s.address)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	_, err := s.conn.Write(entry)
+	if errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS) {
+		// too big for a datagram
+		return s.sendInMemfd(entry)
+	}
+	if err != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	return err
+}
+
+// NOT THREAD SAFE! Already assumes Lock is established.
+func (s *journalSocket) sendInMemfd(entry []byte) error {
+	fd, err := unix.MemfdCreate("journal-entry", unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return fmt.Errorf("failed to create memfd for a big journal entry: %v", err)
+	}
+	file := os.NewFile(uintptr(fd), "journal-entry")
+	defer file.Close()
+	if _, err := file.Write(entry); err != nil {
+		return fmt.Errorf("failed to write memfd for a big journal entry: %v", err)
+	}
+	// journald accepts only sealed memfds
+	if _, err := unix.FcntlInt(uintptr(fd), unix.F_ADD_SEALS, unix.F_SEAL_SHRINK|unix.F_SEAL_GROW|unix.F_SEAL_WRITE|unix.F_SEAL_SEAL); err != nil {
+		return fmt.Errorf("failed to seal memfd for a big journal entry: %v", err)
+	}
+	// net.UnixConn does not allow WriteMsgUnix() on connected datagram sockets - so we go to the raw socket
+	rawConn, err := s.conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var sendErr error
+	err = rawConn.Write(func(socketFd uintptr) bool {
+		sendErr = unix.Sendmsg(int(socketFd), nil, unix.UnixRights(fd), nil, 0)
+		return sendErr != unix.EAGAIN
+	})
+	return errors.Join(err, sendErr)
+}
+
+func (s *journalSocket) close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
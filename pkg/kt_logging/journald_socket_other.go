@@ -0,0 +1,20 @@
+//go:build !linux
+
+package kt_logging
+
+import "fmt"
+
+// journald exists only on linux
+type journalSocket struct{}
+
+func newJournalSocket(socketPath string) (*journalSocket, error) {
+	return nil, fmt.Errorf("journald is supported only on linux")
+}
+
+func (s *journalSocket) send(entry []byte) error {
+	return fmt.Errorf("journald is supported only on linux")
+}
+
+func (s *journalSocket) close() error {
+	return nil
+}
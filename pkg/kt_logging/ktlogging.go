@@ -1,13 +1,15 @@
 package kt_logging
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
-	"gopkg.in/natefinch/lumberjack.v2"
 	//"gopkg.in/yaml.v3"
 )
 
@@ -19,6 +21,12 @@ const (
 	WarningLevel LogLevel = 2
 	InfoLevel    LogLevel = 3
 	DebugLevel   LogLevel = 4
+	TraceLevel   LogLevel = 5
+
+	// PanicLevel and FatalLevel can not be configured - they mark the log events fired by the .Panic() and .Fatal() methods.
+	// Regarding filtering these events are treated as ErrorLevel events.
+	PanicLevel LogLevel = 0xFE
+	FatalLevel LogLevel = 0xFF
 
 	_ROOT_NAME string = "root"
 )
@@ -28,42 +36,202 @@ var loggers map[string]*Logger
 // we need locks to avoid concurrent map operations
 var loggersLock = new(sync.RWMutex)
 
+// the currently active handlers - we need to sync and close them once they are replaced or on shutdown
+var activeHandlers handlerSet
+
 // this is a set of key-value pairs which are added to every log events
-// You can use the getter/setter to change these values!
-var globalLabels []Label
-var zapGlobalLabels []zap.Field
+// You can use the getter/setter to change these values! They can be changed while logging is going on - so the labels along
+// with their zap fields are swapped atomically
+var globalLabels atomic.Pointer[globalLabelSet]
+
+type globalLabelSet struct {
+	labels    []Label
+	zapFields []zap.Field
+}
 
 // returns the current GlobalLabels - key-value pairs attached to all log events
 func GetGlobalLabels() []Label {
-	return globalLabels
+	if current := globalLabels.Load(); current != nil {
+		return current.labels
+	}
+	return nil
+}
+
+// returns the current GlobalLabels converted to zap fields
+func getZapGlobalLabels() []zap.Field {
+	if current := globalLabels.Load(); current != nil {
+		return current.zapFields
+	}
+	return nil
 }
 
 // you can change the GlobalLabels with this - the key-value pairs attached to all log events
 func SetGlobalLabels(labels []Label) {
-	globalLabels = labels
 	// let's convert immediately to Zap fields
-	zapGlobalLabels = toZapFieldArray(labels)
+	globalLabels.Store(&globalLabelSet{labels: labels, zapFields: toZapFieldArray(labels)})
 }
 
 // Initializing the logging from the .yaml or .json config file available on the given path
 func InitFromConfig(cfgPath string) error {
+	return ReloadFromConfig(cfgPath)
+}
+
+// Re-reads the .yaml or .json config file available on the given path and applies it - this can be invoked anytime while the
+// app is running. Logger instances you already hold keep working, they just pick up the new levels and handlers.
+// If the config can not be read or is invalid then an error is returned and the current setup remains untouched.
+func ReloadFromConfig(cfgPath string) error {
 	// read the config file
 	configModel, err := parseFromJsonOrYaml(cfgPath)
 	if err != nil {
 		return err
 	}
+	return applyConfig(configModel)
+}
+
+// creates the loggers and handlers from the given config and swaps them in place of the current ones
+func applyConfig(configModel ConfigModel) error {
 	// create and initialize loggers based on that
-	configuredLoggers, err := initLoggersFromConfig(configModel)
+	configuredLoggers, configuredHandlers, err := initLoggersFromConfig(configModel)
 	if err != nil {
+		configuredHandlers.close()
 		return err
 	}
 
 	// all good - lets store this
+	loggersLock.Lock()
+	previousLoggers := loggers
+	previousHandlers := activeHandlers
+	previousFilters := collectLoggerFilters()
 	loggers = configuredLoggers
+	activeHandlers = configuredHandlers
+	if previousLoggers != nil {
+		adoptPreviousLoggers(previousLoggers)
+	}
+	loggersLock.Unlock()
+
+	// the repetitions held back by the old filters go to the new handlers
+	for _, filter := range previousFilters {
+		filter.flush()
+	}
+	// old handlers are not used anymore - once the events in flight are handled we can close them
+	previousHandlers.retire()
+	previousHandlers.sync()
+	previousHandlers.close()
 
 	return nil
 }
 
+// puts back the Logger instances from the previous setup into the (already swapped) loggers map - so anyone holding a
+// pointer to them will use the new config from now on. NOT THREAD SAFE! Already assumes Lock is established.
+func adoptPreviousLoggers(previousLoggers map[string]*Logger) {
+	notConfigured := []string{}
+	for name, previousLogger := range previousLoggers {
+		configuredLogger, contains := loggers[name]
+		if contains {
+			previousLogger.adoptStateOf(configuredLogger)
+			// it might have followed its parent so far - but now it has its own level
+			previousLogger.inheritsLevel = false
+			loggers[name] = previousLogger
+		} else {
+			notConfigured = append(notConfigured, name)
+		}
+	}
+
+	// the rest were derived from their parents - we re-derive them from the new setup, parents first
+	sort.Slice(notConfigured, func(i, j int) bool {
+		return strings.Count(notConfigured[i], ".") < strings.Count(notConfigured[j], ".")
+	})
+	for _, name := range notConfigured {
+		previousLogger := previousLoggers[name]
+		previousLogger.adoptStateOf(getParentLogger(name))
+		previousLogger.inheritsLevel = true
+		loggers[name] = previousLogger
+	}
+}
+
+// re-applies the level of the parent on all Loggers which are following their parent's level. NOT THREAD SAFE! Already assumes
+// Lock is established.
+func propagateInheritedLevels() {
+	inheriting := []*Logger{}
+	for _, logger := range loggers {
+		if logger.inheritsLevel {
+			inheriting = append(inheriting, logger)
+		}
+	}
+	// parents first - so changes are flowing down the hierarchy
+	sort.Slice(inheriting, func(i, j int) bool {
+		return strings.Count(inheriting[i].name, ".") < strings.Count(inheriting[j].name, ".")
+	})
+	for _, logger := range inheriting {
+		parentLevel := getParentLogger(logger.name).GetLevel()
+		if logger.GetLevel() != parentLevel {
+			logger.storeLevel(parentLevel)
+		}
+	}
+}
+
+// Flushes all the handlers - so everything logged so far is really written out. Handlers are synced only once even if they
+// are used by multiple Loggers. Errors of the handlers are collected and returned together.
+func Sync() error {
+	loggersLock.RLock()
+	handlers := activeHandlers
+	loggersLock.RUnlock()
+	return handlers.sync()
+}
+
+// Flushes and closes all the handlers (e.g. rolling files) - you should invoke this before your app exits so the tail of
+// the log is not lost. After this all Loggers are silent, subsequent logging is a no-op until the logging is initialized again
+// (see InitFromConfig()).
+// If the given context is done before the handlers are closed then the context error is returned.
+func Shutdown(ctx context.Context) error {
+	// the repetitions held back by the filters should not be lost
+	loggersLock.RLock()
+	filters := collectLoggerFilters()
+	loggersLock.RUnlock()
+	for _, filter := range filters {
+		filter.flush()
+	}
+
+	loggersLock.Lock()
+	handlers := activeHandlers
+	activeHandlers = handlerSet{}
+	if loggers == nil {
+		// so we will not fall back to the default config
+		loggers = map[string]*Logger{_ROOT_NAME: newLogger(_ROOT_NAME, NoneLevel, map[string]*configuredHandler{})}
+	}
+	for _, logger := range loggers {
+		logger.state.Store(&loggerState{level: logger.GetLevel(), handlers: map[string]*configuredHandler{}})
+	}
+	loggersLock.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		handlers.retire()
+		done <- errors.Join(handlers.sync(), handlers.close())
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// returns the filters of the Loggers - the ones shared by multiple Loggers are listed only once
+// NOT THREAD SAFE! Already assumes Lock is established
+func collectLoggerFilters() []*loggerFilter {
+	filters := []*loggerFilter{}
+	seen := map[*loggerFilter]bool{}
+	for _, logger := range loggers {
+		filter := logger.state.Load().filter
+		if filter != nil && !seen[filter] {
+			seen[filter] = true
+			filters = append(filters, filter)
+		}
+	}
+	return filters
+}
+
 // returns a Logger with the given name - if does not exist then a new instance is created with this name and registered
 // note: Loggers are hierarchical
 func GetLogger(loggerName string) *Logger {
@@ -73,6 +241,11 @@ func GetLogger(loggerName string) *Logger {
 	return ctxLogger
 }
 
+// Changes the level of the Logger with the given name in runtime - see Logger.SetLevel()
+func SetLevel(loggerName string, level LogLevel) {
+	GetLogger(loggerName).SetLevel(level)
+}
+
 // just a shortcut to the GetLogger method - for builder style readability stuff
 func With(loggerName string) *Logger {
 	return GetLogger(loggerName)
@@ -83,28 +256,21 @@ func getLogger(loggerName string) *Logger {
 	if loggers == nil {
 		// this means that loggers were not initialised. Create a root logger with default config.
 		var err error
-		loggers, err = initLoggersFromConfig(getDefaultLoggerConfig())
+		loggers, activeHandlers, err = initLoggersFromConfig(getDefaultLoggerConfig())
 		if err != nil {
 			panic(fmt.Sprintf("could not create a root logger with default config: %v", err.Error()))
 		}
 	}
 	ctxLogger := loggers[loggerName]
 	if ctxLogger == nil {
-		// let's plit by '.' characters
-		dotIdx := strings.LastIndex(loggerName, ".")
-		var parentLogger *Logger
-		if dotIdx > 0 {
-			// ok it seems to be a structured name... let's retry with logger by cutting down the last part
-			parentLogger = getLogger(loggerName[0:dotIdx])
-		} else {
-			// there are no dots - we return the "root" logger
-			parentLogger = getRootLogger()
-		}
+		parentLogger := getParentLogger(loggerName)
 
 		// let's register a copy of this returned parentLogger so next time we find this faster
 		loggerCopy := parentLogger.clone()
 		// let's rename the clone
 		loggerCopy.name = loggerName
+		// and it keeps following the level of the parent
+		loggerCopy.inheritsLevel = true
 		// register the clone
 		loggers[loggerName] = loggerCopy
 		ctxLogger = loggerCopy
@@ -112,6 +278,18 @@ func getLogger(loggerName string) *Logger {
 	return ctxLogger
 }
 
+// internal method to get the parent of a logger - NOT THREAD SAFE! Already assumes Lock is established so no race condition!
+func getParentLogger(loggerName string) *Logger {
+	// let's plit by '.' characters
+	dotIdx := strings.LastIndex(loggerName, ".")
+	if dotIdx > 0 {
+		// ok it seems to be a structured name... let's retry with logger by cutting down the last part
+		return getLogger(loggerName[0:dotIdx])
+	}
+	// there are no dots - we return the "root" logger
+	return getRootLogger()
+}
+
 func getRootLogger() *Logger {
 	rootLogger, contains := loggers[_ROOT_NAME]
 	if !contains {
@@ -140,6 +318,30 @@ func getDefaultLoggerConfig() ConfigModel {
 	}
 }
 
+// returns the level as string - in the same form it can be used in the config
+func (level LogLevel) String() string {
+	switch level {
+	case NoneLevel:
+		return "none"
+	case ErrorLevel:
+		return "error"
+	case WarningLevel:
+		return "warning"
+	case InfoLevel:
+		return "info"
+	case DebugLevel:
+		return "debug"
+	case TraceLevel:
+		return "trace"
+	case PanicLevel:
+		return "panic"
+	case FatalLevel:
+		return "fatal"
+	default:
+		return fmt.Sprintf("LogLevel(%d)", uint8(level))
+	}
+}
+
 func parseLogLevelString(levelStr string) (LogLevel, error) {
 	var level LogLevel
 	switch strings.ToLower(levelStr) {
@@ -153,93 +355,96 @@ func parseLogLevelString(levelStr string) (LogLevel, error) {
 		level = InfoLevel
 	case "debug":
 		level = DebugLevel
+	case "trace":
+		level = TraceLevel
 	default:
 		return 0, fmt.Errorf("invalid log level '%v'", levelStr)
 	}
 	return level, nil
 }
 
-// creates all Loggers and also Handlers (underlying Zap Loggers) - based on the config we have
-func initLoggersFromConfig(config ConfigModel) (map[string]*Logger, error) {
+// parses the level of a Handler - on top of the Logger levels "dpanic", "panic" and "fatal" are accepted too (as zap did it in
+// earlier versions). Handlers on these levels receive only the events fired by the .Panic() / .Fatal() methods.
+func parseHandlerLevelString(levelStr string) (LogLevel, error) {
+	switch strings.ToLower(levelStr) {
+	case "dpanic", "panic":
+		return PanicLevel, nil
+	case "fatal":
+		return FatalLevel, nil
+	default:
+		return parseLogLevelString(levelStr)
+	}
+}
 
-	loggers := make(map[string]*Logger)
+// returns TRUE if a Handler on this level receives the events of the given level
+func (level LogLevel) isHandlerEnabled(eventLevel LogLevel) bool {
+	switch level {
+	case PanicLevel:
+		return eventLevel == PanicLevel || eventLevel == FatalLevel
+	case FatalLevel:
+		return eventLevel == FatalLevel
+	default:
+		return level >= eventLevel.filterLevel()
+	}
+}
 
-	// let's start with the handlers - as we will create a Zap logger for each entry there
+// returns TRUE if this is one of the levels defined above
+func (level LogLevel) isKnown() bool {
+	return level <= TraceLevel || level == PanicLevel || level == FatalLevel
+}
 
-	zapEncoderConfig := zapcore.EncoderConfig{
-		MessageKey:  "message",
-		LevelKey:    "level",
-		TimeKey:     "time",
-		EncodeLevel: zapcore.LowercaseLevelEncoder,
-		EncodeTime:  zapcore.RFC3339NanoTimeEncoder,
+// the level of an event regarding filtering - Panic and Fatal events are filtered like Error events
+func (level LogLevel) filterLevel() LogLevel {
+	if level == PanicLevel || level == FatalLevel {
+		return ErrorLevel
 	}
-	zapLoggers := make(map[string]*zap.Logger)
+	return level
+}
+
+// creates all Loggers and also Handlers - based on the config we have
+// along with the Loggers the Handlers are also returned so they can be synced and closed later
+// note: in case of error the already created Handlers are returned too - so they can be closed
+func initLoggersFromConfig(config ConfigModel) (map[string]*Logger, handlerSet, error) {
+
+	loggers := make(map[string]*Logger)
+	createdHandlers := newHandlerSet()
+
+	// let's start with the handlers - as Loggers are referring to them
 	for key, element := range config.Handlers {
-		// let's assemble a Zap config object!
-		zapLevel, err := zap.ParseAtomicLevel(element.Level)
+		handler, err := newConfiguredHandler(key, element)
 		if err != nil {
-			return loggers, fmt.Errorf("unkown log level '%v' in config at /handlers/%v", element.Level, key)
-		}
-
-		var zapLogger *zap.Logger
-		if element.RollingFile == nil {
-			zapCfg := zap.Config{
-				Level:             zapLevel,
-				Encoding:          element.Encoding,
-				OutputPaths:       element.OutputPaths,
-				EncoderConfig:     zapEncoderConfig,
-				DisableCaller:     true,
-				DisableStacktrace: true,
-			}
-			zapLogger = zap.Must(zapCfg.Build())
-		} else {
-			if len(element.OutputPaths) > 0 {
-				// this is not allowed!
-				return loggers, fmt.Errorf("if you use 'rollingFile' on a handler then you can not use 'outputPaths' as well in config at /handlers/%v", key)
-			}
-			log := &lumberjack.Logger{
-				Filename:   element.RollingFile.File,       // Location of the log file
-				MaxSize:    element.RollingFile.MaxSizeMb,  // Maximum file size (in MB)
-				MaxBackups: element.RollingFile.MaxBackups, // Maximum number of old files to retain
-				MaxAge:     element.RollingFile.MaxAgeDays, // Maximum number of days to retain old files
-				Compress:   element.RollingFile.Compress,   // Whether to compress/archive old files
-				LocalTime:  true,                           // Use local time for timestamps
-			}
-			writer := zapcore.AddSync(log)
-			var encoder zapcore.Encoder
-			if element.Encoding == "console" {
-				encoder = zapcore.NewConsoleEncoder(zapEncoderConfig)
-			} else {
-				encoder = zapcore.NewJSONEncoder(zapEncoderConfig)
-			}
-			core := zapcore.NewCore(encoder, writer, zapLevel)
-			zapLogger = zap.New(core)
+			return loggers, createdHandlers, err
 		}
-		zapLoggers[key] = zapLogger
+		createdHandlers.handlers[key] = handler
 	}
 
 	// cool! now let's deal with the /loggers part!
 	for key, element := range config.Loggers {
-		var handlers = map[string]*zap.Logger{}
+		var handlers = map[string]*configuredHandler{}
 		for _, handlerName := range element.HandlerNames {
-			handler, contains := zapLoggers[handlerName]
+			handler, contains := createdHandlers.handlers[handlerName]
 			if !contains {
-				return loggers, fmt.Errorf("problem in config /loggers/%v: invalid handler reference, handler '%v' does not exist", key, handlerName)
+				return loggers, createdHandlers, fmt.Errorf("problem in config /loggers/%v: invalid handler reference, handler '%v' does not exist", key, handlerName)
 			}
 			handlers[handlerName] = handler
 		}
 		level, err := parseLogLevelString(element.Level)
 		if err != nil {
-			return loggers, fmt.Errorf("problem in config /loggers/%v: %v", key, err)
+			return loggers, createdHandlers, fmt.Errorf("problem in config /loggers/%v: %v", key, err)
+		}
+		filter, err := newLoggerFilter(element)
+		if err != nil {
+			return loggers, createdHandlers, fmt.Errorf("problem in config /loggers/%v: %v", key, err)
 		}
 		logger := newLogger(key, level, handlers)
+		logger.state.Store(&loggerState{level: level, handlers: handlers, filter: filter, generation: createdHandlers.generation})
 		loggers[key] = logger
 	}
 
 	if _, contains := loggers["root"]; !contains {
 		// "root" logger definition is mandatory
-		return loggers, fmt.Errorf("log config file must define \"root\" logger")
+		return loggers, createdHandlers, fmt.Errorf("log config file must define \"root\" logger")
 	}
 
-	return loggers, nil
+	return loggers, createdHandlers, nil
 }
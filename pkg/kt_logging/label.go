@@ -1,6 +1,10 @@
 package kt_logging
 
-import "go.uber.org/zap"
+import (
+	"strconv"
+
+	"go.uber.org/zap"
+)
 
 // constructs a String label with the given key and value.
 func StringLabel(key string, val string) Label {
@@ -70,6 +74,22 @@ func (l Label) GetFloatValue() float64 {
 	return l.floatValue
 }
 
+// returns the value of the Label as string - for outputs which can carry only strings
+func (l Label) valueAsString() string {
+	switch l._type {
+	case BoolType:
+		return strconv.FormatBool(l.boolValue)
+	case IntType:
+		return strconv.FormatInt(l.intValue, 10)
+	case FloatType:
+		return strconv.FormatFloat(l.floatValue, 'g', -1, 64)
+	case StringType:
+		return l.stringValue
+	default:
+		return "!unknown_type!"
+	}
+}
+
 // converts a Label into zap.Field struct
 func (f Label) toZapField() zap.Field {
 	switch f._type {
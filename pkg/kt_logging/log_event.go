@@ -1,5 +1,7 @@
 package kt_logging
 
+import "context"
+
 /*
 LogEvent structs just used internally - when user is adding extra labels to the log event.
 In that case an instance of this struct is created by the Logger and this struct is respponsible to collect up the extra labels
@@ -13,6 +15,8 @@ type LogEvent struct {
 	customLabelList [][]Label
 	// and we also have a simple list of pointers - ppointing to key-value pair
 	customLabels []Label
+	// the context the event belongs to - labels attached to it are added to the event
+	ctx context.Context
 }
 
 // constructor - package private
@@ -37,9 +41,15 @@ func (le LogEvent) WithLabel(label Label) LogEvent {
 	return le
 }
 
+// Decorates the event with the labels attached to the given context (see ContextWithLabels())
+func (le LogEvent) WithContext(ctx context.Context) LogEvent {
+	le.ctx = ctx
+	return le
+}
+
 // making this event - actually makes the log itself
 func (le LogEvent) logWithLogger(level LogLevel, message string, messageParams ...any) {
-	if le.logger.isFilteredOut(level) || len(le.logger.handlers) == 0 {
+	if le.logger.isFilteredOut(level) || !le.logger.hasHandlers() {
 		// we skip this - as this log event will not happen for sure no point to make further efforts
 		return
 	}
@@ -47,14 +57,25 @@ func (le LogEvent) logWithLogger(level LogLevel, message string, messageParams .
 	// this event will be logged - so it makes sense to compile and put together everything!
 
 	var joinedLabels = []Label{}
+	// labels coming from the context first - so labels added directly to the event are coming later
+	joinedLabels = append(joinedLabels, allLabelsOfContext(le.ctx)...)
 	// add the custom things
 	for _, customLabelsArr := range le.customLabelList {
 		joinedLabels = append(joinedLabels, customLabelsArr...)
 	}
 	joinedLabels = append(joinedLabels, le.customLabels...)
 
+	ctx := le.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	// finally, lets do the log!
-	le.logger.log(level, joinedLabels, message, messageParams...)
+	le.logger.log(ctx, level, joinedLabels, message, messageParams...)
+}
+
+// Fires a log event on Trace level
+func (le LogEvent) Trace(message string, messageParams ...any) {
+	le.logWithLogger(TraceLevel, message, messageParams...)
 }
 
 // Fires a log event on Debug level
@@ -76,3 +97,15 @@ func (le LogEvent) Warn(message string, messageParams ...any) {
 func (le LogEvent) Error(message string, messageParams ...any) {
 	le.logWithLogger(ErrorLevel, message, messageParams...)
 }
+
+// Fires a log event on Panic level then flushes all handlers and panics with the message
+func (le LogEvent) Panic(message string, messageParams ...any) {
+	le.logWithLogger(PanicLevel, message, messageParams...)
+	terminateAfter(PanicLevel, message, messageParams...)
+}
+
+// Fires a log event on Fatal level then flushes all handlers and terminates the process with os.Exit(1)
+func (le LogEvent) Fatal(message string, messageParams ...any) {
+	le.logWithLogger(FatalLevel, message, messageParams...)
+	terminateAfter(FatalLevel, message, messageParams...)
+}
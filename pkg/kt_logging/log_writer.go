@@ -0,0 +1,86 @@
+// This file contains io.Writer support - for code which can only write into an io.Writer (e.g. the standard "log" package or
+// http.Server.ErrorLog)
+//
+// Everything written is split into lines and each line becomes a log event of the Logger on the given level.
+
+package kt_logging
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+type logWriter struct {
+	logger *Logger
+	level  LogLevel
+	// guards the buffer - writers can be shared between goroutines
+	lock sync.Mutex
+	// the beginning of a line which was not terminated yet
+	buffer []byte
+}
+
+// Returns an io.Writer which fires a log event on the given level with every line written into it. Incomplete lines are kept
+// until the line is completed by a later write.
+func (l *Logger) Writer(level LogLevel) io.Writer {
+	return &logWriter{logger: l, level: level}
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.buffer = append(w.buffer, p...)
+	for {
+		newLineIdx := bytes.IndexByte(w.buffer, '\n')
+		if newLineIdx < 0 {
+			break
+		}
+		w.logLine(w.buffer[:newLineIdx])
+		w.buffer = w.buffer[newLineIdx+1:]
+	}
+	// let's not keep the already consumed part of the underlying array
+	if len(w.buffer) == 0 {
+		w.buffer = nil
+	} else {
+		w.buffer = append([]byte{}, w.buffer...)
+	}
+	return len(p), nil
+}
+
+func (w *logWriter) logLine(line []byte) {
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+	if len(line) == 0 {
+		return
+	}
+	// the line is not a Printf() style template so we must not resolve it as that
+	w.logger.logMessage(context.Background(), time.Time{}, w.level, []Label{}, string(line))
+}
+
+// Returns a standard library *log.Logger which writes into the Logger with the given name on the given level. Useful e.g. for
+// http.Server.ErrorLog
+func NewStdLog(loggerName string, level LogLevel) *log.Logger {
+	return log.New(GetLogger(loggerName).Writer(level), "", 0)
+}
+
+// Redirects the output of the standard library "log" package (log.Printf() and co) into the Logger with the given name on the
+// given level. Returns a function which restores the original setup of the "log" package.
+func RedirectStdLog(loggerName string, level LogLevel) func() {
+	previousFlags := log.Flags()
+	previousPrefix := log.Prefix()
+	previousWriter := log.Writer()
+
+	// time is added by our handlers anyways
+	log.SetFlags(0)
+	log.SetPrefix("")
+	log.SetOutput(GetLogger(loggerName).Writer(level))
+
+	return func() {
+		log.SetFlags(previousFlags)
+		log.SetPrefix(previousPrefix)
+		log.SetOutput(previousWriter)
+	}
+}
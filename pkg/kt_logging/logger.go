@@ -6,30 +6,57 @@
 package kt_logging
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
 
 	"go.uber.org/zap"
 )
 
+// the level and handlers of a Logger - these can be swapped in runtime (e.g. on config reload) so we keep them together in an
+// immutable struct and replace the whole thing atomically
+type loggerState struct {
+	level    LogLevel
+	handlers map[string]*configuredHandler
+	// dedup / rate limit - nil if not configured
+	filter *loggerFilter
+	// the generation of the handlers - nil if there are no handlers
+	generation *handlerGeneration
+}
+
 type Logger struct {
-	name     string                 // package private field
-	level    LogLevel               // package private field
-	handlers map[string]*zap.Logger // package private field
+	name  string                      // package private field
+	state atomic.Pointer[loggerState] // package private field
+	// TRUE if the level of the Logger was not configured explicitly but it follows the level of its parent - guarded by loggersLock
+	inheritsLevel bool
 }
 
 // Constructor of the Logger - package private
-func newLogger(name string, level LogLevel, handlers map[string]*zap.Logger) *Logger {
-	instance := &Logger{name: name, level: level, handlers: handlers}
+func newLogger(name string, level LogLevel, handlers map[string]*configuredHandler) *Logger {
+	instance := &Logger{name: name}
+	instance.state.Store(&loggerState{level: level, handlers: handlers})
 	return instance
 }
 
 // returns a clone of the logger - after this the 2 instances are not connected anyhow
 func (l *Logger) clone() *Logger {
-	loggers_clone := map[string]*zap.Logger{}
-	for key, value := range l.handlers {
-		loggers_clone[key] = value
-	}
-	return newLogger(l.name, l.level, loggers_clone)
+	instance := &Logger{name: l.name}
+	// the state is immutable so it is safe to share it
+	instance.state.Store(l.state.Load())
+	return instance
+}
+
+// takes over the level and handlers of the given Logger
+func (l *Logger) adoptStateOf(other *Logger) {
+	l.state.Store(other.state.Load())
+}
+
+// changes the level while keeping the handlers
+func (l *Logger) storeLevel(level LogLevel) {
+	state := l.state.Load()
+	l.state.Store(&loggerState{level: level, handlers: state.handlers, filter: state.filter, generation: state.generation})
 }
 
 // returns the name of the Logger - this can not change after instantiation
@@ -39,84 +66,203 @@ func (l *Logger) GetName() string {
 
 // returns the level
 func (l *Logger) GetLevel() LogLevel {
-	return l.level
+	return l.state.Load().level
 }
 
-// returns the attached Handlers
+// Changes the level of the Logger in runtime. Child Loggers which do not have an explicitly configured (or set) level are
+// following this change.
+// Please note: if the config is reloaded (see ReloadFromConfig()) the level is overwritten by the config again.
+func (l *Logger) SetLevel(level LogLevel) {
+	loggersLock.Lock()
+	defer loggersLock.Unlock()
+	l.inheritsLevel = false
+	l.storeLevel(level)
+	propagateInheritedLevels()
+}
+
+// restores a level (and whether it was inherited from the parent) saved earlier
+func (l *Logger) restoreLevel(level LogLevel, inheritsLevel bool) {
+	loggersLock.Lock()
+	defer loggersLock.Unlock()
+	l.inheritsLevel = inheritsLevel
+	if !inheritsLevel {
+		l.storeLevel(level)
+	}
+	propagateInheritedLevels()
+}
+
+// returns the zap Loggers of the attached zap based Handlers - Handlers of other types are not in the map. Use
+// GetOutputHandlers() to get all of them
 func (l *Logger) GetHandlers() map[string]*zap.Logger {
-	return l.handlers
+	zapLoggers := map[string]*zap.Logger{}
+	for name, configured := range l.state.Load().handlers {
+		if zapHandler, isZap := unwrapHandler(configured.handler).(*ZapHandler); isZap {
+			zapLoggers[name] = zapHandler.GetZapLogger()
+		}
+	}
+	return zapLoggers
+}
+
+// returns the attached Handlers
+func (l *Logger) GetOutputHandlers() map[string]Handler {
+	handlers := map[string]Handler{}
+	for name, configured := range l.state.Load().handlers {
+		handlers[name] = configured.handler
+	}
+	return handlers
+}
+
+// returns TRUE if the Logger has (output) handlers at the moment
+func (l *Logger) hasHandlers() bool {
+	return len(l.state.Load().handlers) > 0
 }
 
 func (l *Logger) isFilteredOut(level LogLevel) bool {
-	return l.level < level
+	return l.state.Load().level < level.filterLevel()
 }
 
 // returns TRUE if Logger would output Error level logs due to its current configuration - FALSE otherwise
 func (l *Logger) IsErrorEnabled() bool {
-	return l.level >= ErrorLevel && len(l.handlers) > 0
+	state := l.state.Load()
+	return state.level >= ErrorLevel && len(state.handlers) > 0
 }
 
 // returns TRUE if Logger would output Warning level logs due to its current configuration - FALSE otherwise
 func (l *Logger) IsWarningEnabled() bool {
-	return l.level >= WarningLevel && len(l.handlers) > 0
+	state := l.state.Load()
+	return state.level >= WarningLevel && len(state.handlers) > 0
 }
 
 // returns TRUE if Logger would output Info level logs due to its current configuration - FALSE otherwise
 func (l *Logger) IsInfoEnabled() bool {
-	return l.level >= InfoLevel && len(l.handlers) > 0
+	state := l.state.Load()
+	return state.level >= InfoLevel && len(state.handlers) > 0
 }
 
 // returns TRUE if Logger would output Debug level logs due to its current configuration - FALSE otherwise
 func (l *Logger) IsDebugEnabled() bool {
-	return l.level >= DebugLevel && len(l.handlers) > 0
+	state := l.state.Load()
+	return state.level >= DebugLevel && len(state.handlers) > 0
+}
+
+// returns TRUE if Logger would output Trace level logs due to its current configuration - FALSE otherwise
+func (l *Logger) IsTraceEnabled() bool {
+	state := l.state.Load()
+	return state.level >= TraceLevel && len(state.handlers) > 0
 }
 
 // Returns TRUE if Logger would not output anything due to its current configuration. This is either because  it's log level is None or does not have any
 // (output) handlers at the moment
 func (l *Logger) IsSilent() bool {
-	return l.level == NoneLevel || len(l.handlers) == 0
+	state := l.state.Load()
+	return state.level == NoneLevel || len(state.handlers) == 0
 }
 
 // internally used method to do the log
-func (l *Logger) log(level LogLevel, customLabels []Label, message string, messageParams ...any) {
+func (l *Logger) log(ctx context.Context, level LogLevel, customLabels []Label, message string, messageParams ...any) {
+
+	// we work with a snapshot of the state - so a concurrent config reload can not mess up this log event
+	state := l.state.Load()
 
 	// filter for level and not having any handlers (output)
-	if l.isFilteredOut(level) || len(l.handlers) == 0 {
+	if state.level < level.filterLevel() || len(state.handlers) == 0 {
+		return
+	}
+
+	if !level.isKnown() || level == NoneLevel {
+		// OK someone has sent us unknown log level
+		// we dont want to lose this log event but we need to note the problem - so let's log it on Warning level
+		l.log(ctx, WarningLevel, customLabels, "the following message was logged on unkown log level! Original message: "+message, messageParams...)
 		return
 	}
 
 	// this event will be logged - so it makes sense to compile and put together everything!
+	record := LogRecord{
+		Time:       time.Now(),
+		Level:      level,
+		LoggerName: l.name,
+		// lets build the log string
+		Message:         fmt.Sprintf(message, messageParams...),
+		MessageTemplate: message,
+		Labels:          customLabels,
+		Context:         ctx,
+	}
+	l.fire(state, record)
+}
 
-	// lets build the log string
-	msg := fmt.Sprintf(message, messageParams...)
+// internally used method to log a message which is not a Printf() style template but the final message (e.g. coming from slog
+// or an io.Writer) - so it is its own template. If the time is zero then the current time is used.
+func (l *Logger) logMessage(ctx context.Context, eventTime time.Time, level LogLevel, customLabels []Label, message string) {
+	state := l.state.Load()
+	if state.level < level.filterLevel() || len(state.handlers) == 0 {
+		return
+	}
+	if !level.isKnown() || level == NoneLevel {
+		l.logMessage(ctx, eventTime, WarningLevel, customLabels, "the following message was logged on unkown log level! Original message: "+message)
+		return
+	}
+	if eventTime.IsZero() {
+		eventTime = time.Now()
+	}
+	l.fire(state, LogRecord{
+		Time:            eventTime,
+		Level:           level,
+		LoggerName:      l.name,
+		Message:         message,
+		MessageTemplate: message,
+		Labels:          customLabels,
+		Context:         ctx,
+	})
+}
 
-	// we add the name of the logger
-	var joinedLabels = []zap.Field{zap.String("logger", l.name)}
-	// and context variables - if exists
-	if len(zapGlobalLabels) > 0 {
-		joinedLabels = append(joinedLabels, zapGlobalLabels...)
+// passes the log event through the filters of the Logger (if any) then sends it to the handlers
+func (l *Logger) fire(state *loggerState, record LogRecord) {
+	if state.filter != nil && !state.filter.allow(l, record) {
+		return
 	}
-	joinedLabels = append(joinedLabels, toZapFieldArray(customLabels)...)
-
-	// now lets use all underlying Zap loggers and send the log event to each
-	for _, zapLogger := range l.handlers {
-		switch level {
-		case ErrorLevel:
-			zapLogger.Error(msg, joinedLabels...)
-		case WarningLevel:
-			zapLogger.Warn(msg, joinedLabels...)
-		case InfoLevel:
-			zapLogger.Info(msg, joinedLabels...)
-		case DebugLevel:
-			zapLogger.Debug(msg, joinedLabels...)
-		default:
-			// OK someone has sent us unknown log level
-			// we dont want to lose this log event but we need to note the problem - so let's log it on Warning level
-			l.log(WarningLevel, customLabels, "the following message was logged on unkown log level! Original message: "+message, messageParams...)
+	l.dispatch(state, record)
+}
+
+// sends the log event to all the handlers of the state passing their level filtering
+func (l *Logger) dispatch(state *loggerState, record LogRecord) {
+	if generation := state.generation; generation != nil {
+		generation.lock.RLock()
+		if generation.retired {
+			generation.lock.RUnlock()
+			// the handlers were swapped since we took the snapshot (and might be closed already) - the current ones get it
+			if current := l.state.Load(); current != state {
+				l.dispatch(current, record)
+			}
+			return
+		}
+		defer generation.lock.RUnlock()
+	}
+
+	level := record.Level
+	for _, configured := range state.handlers {
+		if !configured.level.isHandlerEnabled(level) {
+			continue
+		}
+		if err := configured.handler.Handle(record); err != nil {
+			reportHandlerError(configured.name, err)
 		}
 	}
 }
 
+// we can not log problems of the handlers via handlers... so they go to STDERR
+func reportHandlerError(handlerName string, err error) {
+	fmt.Fprintf(os.Stderr, "kt_logging: handler '%v' failed to handle log event: %v\n", handlerName, err)
+}
+
+// invoked after a Panic or Fatal event was logged - flushes the handlers then panics or exits the process
+func terminateAfter(level LogLevel, message string, messageParams ...any) {
+	Sync()
+	if level == FatalLevel {
+		os.Exit(1)
+	}
+	panic(fmt.Sprintf(message, messageParams...))
+}
+
 // Decorates the upcoming LogEvent (when you invoke .info(), .error() etc method the LogEvent is fired) with the given labels.
 // Please note: the labels will be just used in the upcoming LogEvent and after that forgotten!
 func (l *Logger) WithLabels(labels []Label) LogEvent {
@@ -134,28 +280,53 @@ func (l *Logger) WithLabel(label Label) LogEvent {
 	return le
 }
 
+// Returns a LogEvent decorated with the labels attached to the given context (see ContextWithLabels()). You can add further
+// labels and when you invoke .Info(), .Error() etc method the LogEvent is fired.
+func (l *Logger) Ctx(ctx context.Context) LogEvent {
+	le := newLogEvent(l)
+	le = le.WithContext(ctx)
+	return le
+}
+
 // logs the given message resolved with (optional) messageParams (Printf() style) on the given log level
 // in case the the message is filtered out due to configured log level then the message string is not built at all
 func (l *Logger) Log(level LogLevel, message string, messageParams ...any) {
-	l.log(level, []Label{}, message, messageParams...)
+	l.log(context.Background(), level, []Label{}, message, messageParams...)
+}
+
+// Wrapper around .Log() function - firing a log event on Trace level
+func (l *Logger) Trace(message string, messageParams ...any) {
+	l.log(context.Background(), TraceLevel, []Label{}, message, messageParams...)
 }
 
 // Wrapper around .Log() function - firing a log event on Debug level
 func (l *Logger) Debug(message string, messageParams ...any) {
-	l.log(DebugLevel, []Label{}, message, messageParams...)
+	l.log(context.Background(), DebugLevel, []Label{}, message, messageParams...)
 }
 
 // Wrapper around .Log() function - firing a log event on Info level
 func (l *Logger) Info(message string, messageParams ...any) {
-	l.log(InfoLevel, []Label{}, message, messageParams...)
+	l.log(context.Background(), InfoLevel, []Label{}, message, messageParams...)
 }
 
 // Wrapper around .Log() function - firing a log event on Warning level
 func (l *Logger) Warn(message string, messageParams ...any) {
-	l.log(WarningLevel, []Label{}, message, messageParams...)
+	l.log(context.Background(), WarningLevel, []Label{}, message, messageParams...)
 }
 
 // Wrapper around .Log() function - firing a log event on Error level
 func (l *Logger) Error(message string, messageParams ...any) {
-	l.log(ErrorLevel, []Label{}, message, messageParams...)
+	l.log(context.Background(), ErrorLevel, []Label{}, message, messageParams...)
+}
+
+// Fires a log event on Panic level then flushes all handlers and panics with the message
+func (l *Logger) Panic(message string, messageParams ...any) {
+	l.log(context.Background(), PanicLevel, []Label{}, message, messageParams...)
+	terminateAfter(PanicLevel, message, messageParams...)
+}
+
+// Fires a log event on Fatal level then flushes all handlers and terminates the process with os.Exit(1)
+func (l *Logger) Fatal(message string, messageParams ...any) {
+	l.log(context.Background(), FatalLevel, []Label{}, message, messageParams...)
+	terminateAfter(FatalLevel, message, messageParams...)
 }
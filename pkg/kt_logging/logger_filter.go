@@ -0,0 +1,171 @@
+// This file contains the filters configured on Loggers: deduplication of repeated log events (see dedup.go) and rate limiting
+//
+// The rate limit is a token bucket: the Logger can fire 'eventsPerSec' log events per second on average with bursts up to 'burst'
+// events. Events above the limit are dropped - a warning event tells how many were dropped: once events pass again, at latest a
+// second after the first drop (and every second while the flood lasts) and when the filter is flushed (config reload, Shutdown()).
+// Child Loggers share the filters of their parent (just like the handlers).
+
+package kt_logging
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// the dropped events are reported at latest after this
+const _RATE_LIMIT_REPORT_INTERVAL = time.Second
+
+type loggerFilter struct {
+	// nil if not configured
+	deduplicator *deduplicator
+	// nil if not configured
+	rateLimiter *rateLimiter
+}
+
+type rateLimiter struct {
+	lock *sync.Mutex
+	// tokens added per second
+	rate  float64
+	burst float64
+	// the available tokens at lastRefill
+	tokens     float64
+	lastRefill time.Time
+	// number of events dropped since the last report
+	dropped int
+	// the last dropped event - the report goes with its logger name and context
+	lastDropped LogRecord
+	// writes the report
+	emit func(record LogRecord)
+	// fires the report if no event passes meanwhile - nil if nothing was dropped
+	reportTimer *time.Timer
+}
+
+// creates the filter of the Logger - returns nil if the config does not ask for any filtering
+func newLoggerFilter(cfg LoggerConfigModel) (*loggerFilter, error) {
+	if cfg.Dedup == nil && cfg.RateLimit == nil {
+		return nil, nil
+	}
+	filter := &loggerFilter{}
+	if cfg.Dedup != nil {
+		deduplicator, err := newDeduplicator(*cfg.Dedup)
+		if err != nil {
+			return nil, err
+		}
+		filter.deduplicator = deduplicator
+	}
+	if cfg.RateLimit != nil {
+		rateLimiter, err := newRateLimiter(*cfg.RateLimit)
+		if err != nil {
+			return nil, err
+		}
+		filter.rateLimiter = rateLimiter
+	}
+	return filter, nil
+}
+
+func newRateLimiter(model RateLimitModel) (*rateLimiter, error) {
+	if model.EventsPerSec <= 0 {
+		return nil, fmt.Errorf("'eventsPerSec' of the rate limit must be positive")
+	}
+	burst := float64(model.Burst)
+	if burst <= 0 {
+		burst = math.Max(1, math.Ceil(model.EventsPerSec))
+	}
+	return &rateLimiter{lock: new(sync.Mutex), rate: model.EventsPerSec, burst: burst, tokens: burst, lastRefill: time.Now()}, nil
+}
+
+// returns TRUE if the event logged by the Logger can be passed to the handlers. Repetitions held back by the deduplicator are
+// written later, the notice about the events dropped by the rate limit is written right away - both to the handlers of the Logger.
+func (f *loggerFilter) allow(l *Logger, record LogRecord) bool {
+	if record.Level == PanicLevel || record.Level == FatalLevel {
+		// the last words are never filtered
+		return true
+	}
+	emit := func(record LogRecord) {
+		l.dispatch(l.state.Load(), record)
+	}
+	if f.deduplicator != nil && !f.deduplicator.check(record, emit) {
+		return false
+	}
+	if f.rateLimiter != nil && !f.rateLimiter.take(record, emit) {
+		return false
+	}
+	return true
+}
+
+// writes the held back repetitions and the report about the dropped events right away
+func (f *loggerFilter) flush() {
+	if f.deduplicator != nil {
+		f.deduplicator.flush()
+	}
+	if f.rateLimiter != nil {
+		f.rateLimiter.report()
+	}
+}
+
+// takes a token if there is one - returns TRUE if there was. If events were dropped before then the report about them is written
+// first with the given emit function.
+func (r *rateLimiter) take(record LogRecord, emit func(record LogRecord)) bool {
+	r.lock.Lock()
+	if elapsed := record.Time.Sub(r.lastRefill); elapsed > 0 {
+		r.tokens = math.Min(r.burst, r.tokens+elapsed.Seconds()*r.rate)
+		r.lastRefill = record.Time
+	}
+	if r.tokens < 1 {
+		r.dropped++
+		r.lastDropped = record
+		r.emit = emit
+		if r.reportTimer == nil {
+			r.reportTimer = time.AfterFunc(_RATE_LIMIT_REPORT_INTERVAL, r.report)
+		}
+		r.lock.Unlock()
+		return false
+	}
+	r.tokens--
+	notice, hasNotice := r.takeNotice(record.Time)
+	r.lock.Unlock()
+
+	if hasNotice {
+		emit(notice)
+	}
+	return true
+}
+
+// writes the report about the dropped events (if any) right away
+func (r *rateLimiter) report() {
+	r.lock.Lock()
+	notice, hasNotice := r.takeNotice(time.Now())
+	emit := r.emit
+	r.lock.Unlock()
+
+	if hasNotice {
+		emit(notice)
+	}
+}
+
+// returns the report about the dropped events and starts counting again - returns FALSE if nothing was dropped
+// NOT THREAD SAFE! Already assumes Lock is established
+func (r *rateLimiter) takeNotice(now time.Time) (LogRecord, bool) {
+	if r.reportTimer != nil {
+		r.reportTimer.Stop()
+		r.reportTimer = nil
+	}
+	if r.dropped == 0 {
+		return LogRecord{}, false
+	}
+	message := fmt.Sprintf("%d log events were dropped by the rate limit of the logger", r.dropped)
+	notice := LogRecord{
+		Time:            now,
+		Level:           WarningLevel,
+		LoggerName:      r.lastDropped.LoggerName,
+		Message:         message,
+		MessageTemplate: message,
+		Labels:          []Label{IntLabel("rateLimited", int64(r.dropped))},
+		Context:         r.lastDropped.Context,
+	}
+	r.dropped = 0
+	r.lastDropped = LogRecord{}
+	return notice, true
+}
@@ -0,0 +1,99 @@
+package kt_logging
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	_DEFAULT_DIAL_TIMEOUT_MS  = 5000
+	_DEFAULT_WRITE_TIMEOUT_MS = 5000
+	_MIN_REDIAL_DELAY         = 100 * time.Millisecond
+	_MAX_REDIAL_DELAY         = 30 * time.Second
+)
+
+// a network connection which is (re)established on demand - if a write fails the connection is re-dialed and the write is
+// retried once. If dialing fails we do not dial again for a while (exponential backoff) - writes in between fail right away, so
+// an unreachable server does not make every log event wait for the dial timeout.
+type reconnectingConn struct {
+	dial   func(dialer *net.Dialer) (net.Conn, error)
+	dialer *net.Dialer
+	// 0 means no deadline
+	writeTimeout time.Duration
+	lock         sync.Mutex
+	conn         net.Conn
+	// no dial attempt before this time
+	nextDial    time.Time
+	redialDelay time.Duration
+}
+
+// the dial function should use the given Dialer - so the dial timeout applies
+func newReconnectingConn(dial func(dialer *net.Dialer) (net.Conn, error), dialTimeout time.Duration, writeTimeout time.Duration) *reconnectingConn {
+	return &reconnectingConn{dial: dial, dialer: &net.Dialer{Timeout: dialTimeout}, writeTimeout: writeTimeout}
+}
+
+// writes the given bytes in one go - so datagrams are not split
+func (c *reconnectingConn) write(p []byte) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// if we had a connection then the failure might be because the server closed it meanwhile so we retry with a new one
+	retry := c.conn != nil
+	if err := c.writeOnce(p); err != nil {
+		if !retry {
+			return err
+		}
+		return c.writeOnce(p)
+	}
+	return nil
+}
+
+// NOT THREAD SAFE! Already assumes Lock is established.
+func (c *reconnectingConn) writeOnce(p []byte) error {
+	isNewConn := c.conn == nil
+	if isNewConn {
+		if time.Now().Before(c.nextDial) {
+			return fmt.Errorf("not connected - next attempt at %v", c.nextDial.Format(time.RFC3339Nano))
+		}
+		conn, err := c.dial(c.dialer)
+		if err != nil {
+			c.backOff()
+			return err
+		}
+		c.conn = conn
+	}
+	if c.writeTimeout > 0 {
+		// a peer which does not read must not block the logging forever
+		c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	}
+	if _, err := c.conn.Write(p); err != nil {
+		c.conn.Close()
+		c.conn = nil
+		if isNewConn {
+			// the server accepts connections but they do not work - this is just like a failed dial
+			c.backOff()
+		}
+		return err
+	}
+	c.redialDelay = 0
+	return nil
+}
+
+// NOT THREAD SAFE! Already assumes Lock is established.
+func (c *reconnectingConn) backOff() {
+	c.redialDelay = min(max(c.redialDelay*2, _MIN_REDIAL_DELAY), _MAX_REDIAL_DELAY)
+	c.nextDial = time.Now().Add(c.redialDelay)
+}
+
+func (c *reconnectingConn) close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
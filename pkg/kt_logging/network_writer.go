@@ -0,0 +1,193 @@
+// This file contains the writer behind the 'network' output of the "zap" handler - streaming the encoded log events to a
+// TCP or UDP endpoint (e.g. a local Fluent Bit / Vector agent)
+//
+// Writing never waits for the network: encoded events are put into a bounded in-memory buffer and a background goroutine sends
+// them. While the endpoint is not reachable events are kept in the buffer and the connection is retried with exponential
+// backoff. If the buffer is full the configured overflow policy applies.
+
+package kt_logging
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	_DEFAULT_NETWORK_BUFFER_SIZE         = 1000
+	_DEFAULT_NETWORK_MAX_RECONNECT_DELAY = 30 * time.Second
+	_NETWORK_MIN_RECONNECT_DELAY         = 100 * time.Millisecond
+	_NETWORK_IO_TIMEOUT                  = 5 * time.Second
+)
+
+type networkWriter struct {
+	protocol          string
+	address           string
+	framing           string
+	maxReconnectDelay time.Duration
+	queue             *boundedQueue[[]byte]
+	conn              net.Conn
+	// TRUE while the last attempt to send failed
+	failing atomic.Bool
+	// closed when the sender goroutine finished
+	senderDone chan struct{}
+	// closed when Close() is invoked - interrupts the waiting between reconnect attempts
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+func newNetworkWriter(model NetworkModel) (*networkWriter, error) {
+	switch model.Protocol {
+	case "tcp", "udp":
+	default:
+		return nil, fmt.Errorf("unknown network protocol '%v' - 'tcp' or 'udp' is supported", model.Protocol)
+	}
+	if model.Address == "" {
+		return nil, fmt.Errorf("network 'address' is mandatory")
+	}
+	framing := model.Framing
+	switch framing {
+	case "":
+		framing = _FRAMING_NEWLINE
+	case _FRAMING_NEWLINE, _FRAMING_OCTET_COUNTING:
+	default:
+		return nil, fmt.Errorf("unknown framing '%v' - 'newline' or 'octet-counting' is supported", model.Framing)
+	}
+	overflow, err := parseOverflowPolicy(model.Overflow)
+	if err != nil {
+		return nil, err
+	}
+	bufferSize := model.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = _DEFAULT_NETWORK_BUFFER_SIZE
+	}
+	maxReconnectDelay := time.Duration(model.MaxReconnectDelaySec) * time.Second
+	if maxReconnectDelay <= 0 {
+		maxReconnectDelay = _DEFAULT_NETWORK_MAX_RECONNECT_DELAY
+	}
+
+	writer := &networkWriter{
+		protocol:          model.Protocol,
+		address:           model.Address,
+		framing:           framing,
+		maxReconnectDelay: maxReconnectDelay,
+		queue:             newBoundedQueue[[]byte](bufferSize, overflow),
+		senderDone:        make(chan struct{}),
+		closing:           make(chan struct{}),
+	}
+	go writer.sendLoop()
+	return writer, nil
+}
+
+// receives one encoded log event (zap invokes it once per event)
+func (w *networkWriter) Write(p []byte) (int, error) {
+	if !w.queue.push(w.frame(p)) {
+		return 0, errors.New("network writer is closed")
+	}
+	return len(p), nil
+}
+
+// waits until the buffered events are sent - returns error if the endpoint is not reachable at the moment
+func (w *networkWriter) Sync() error {
+	pending := w.queue.waitIdle(w.failing.Load)
+	if pending > 0 {
+		return fmt.Errorf("%v endpoint %v is not reachable - %d log events are buffered", w.protocol, w.address, pending)
+	}
+	return nil
+}
+
+// tries to send the buffered events (if the endpoint is reachable) then closes the connection
+func (w *networkWriter) Close() error {
+	w.closeOnce.Do(func() {
+		w.queue.close()
+		close(w.closing)
+	})
+	<-w.senderDone
+	return nil
+}
+
+// applies the framing on the encoded event - the encoded event is terminated by a newline already
+func (w *networkWriter) frame(p []byte) []byte {
+	if w.framing == _FRAMING_OCTET_COUNTING && w.protocol == "tcp" {
+		message := p
+		if len(message) > 0 && message[len(message)-1] == '\n' {
+			message = message[:len(message)-1]
+		}
+		return append([]byte(fmt.Sprintf("%d ", len(message))), message...)
+	}
+	// zap reuses its buffers - so we must copy
+	return append([]byte{}, p...)
+}
+
+func (w *networkWriter) sendLoop() {
+	defer close(w.senderDone)
+	reconnectDelay := _NETWORK_MIN_RECONNECT_DELAY
+	for {
+		message, ok := w.queue.pop()
+		if !ok {
+			break
+		}
+		for {
+			err := w.send(message)
+			if err == nil {
+				reconnectDelay = _NETWORK_MIN_RECONNECT_DELAY
+				w.failing.Store(false)
+				break
+			}
+			w.failing.Store(true)
+			w.queue.notify()
+			if w.queue.isClosed() {
+				// we are shutting down and the endpoint is not reachable - no point to wait
+				w.queue.done()
+				w.discardRest()
+				return
+			}
+			select {
+			case <-time.After(reconnectDelay):
+			case <-w.closing:
+			}
+			reconnectDelay = min(reconnectDelay*2, w.maxReconnectDelay)
+		}
+		w.queue.done()
+		if dropped := w.queue.takeDropped(); dropped > 0 {
+			fmt.Fprintf(os.Stderr, "kt_logging: %d log events were dropped as the buffer of the %v endpoint %v was full\n", dropped, w.protocol, w.address)
+		}
+	}
+	if w.conn != nil {
+		w.conn.Close()
+	}
+}
+
+// empties the queue without sending
+func (w *networkWriter) discardRest() {
+	for {
+		if _, ok := w.queue.pop(); !ok {
+			break
+		}
+		w.queue.done()
+	}
+	if w.conn != nil {
+		w.conn.Close()
+	}
+}
+
+func (w *networkWriter) send(message []byte) error {
+	if w.conn == nil {
+		conn, err := net.DialTimeout(w.protocol, w.address, _NETWORK_IO_TIMEOUT)
+		if err != nil {
+			return err
+		}
+		w.conn = conn
+	}
+	w.conn.SetWriteDeadline(time.Now().Add(_NETWORK_IO_TIMEOUT))
+	if _, err := w.conn.Write(message); err != nil {
+		w.conn.Close()
+		w.conn = nil
+		return err
+	}
+	return nil
+}
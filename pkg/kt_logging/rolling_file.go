@@ -0,0 +1,537 @@
+// This file contains the writer behind the 'rollingFile' output of the "zap" handler
+//
+// The file is rotated at the boundaries of the configured period (hourly, daily or a duration aligned to midnight), when it
+// would exceed the max size or when it is asked explicitly (see RotateFiles()). Either a fixed file name is used (rotated files
+// are renamed to <name>-<timestamp>.<ext> - plus a counter if the name is taken already) or a file name pattern with time
+// placeholders (then every period writes its own file). After each rotation the registered RotationHooks are invoked then the
+// retention rules - maxBackups, maxAgeDays and compress - are applied on the rotated files in the background. Only files with exactly the names we give are touched - their
+// time is parsed back from the name.
+// The file is never renamed while we keep it open - and on Windows it is opened in a way that others can rename / delete it.
+// So external tools (e.g. logrotate) can also manage the file - in this case the file can be reopened with SIGHUP.
+
+package kt_logging
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// the timestamp in the names of the rotated files - the same lumberjack used so existing backups are still recognized
+	_ROLLING_BACKUP_TIME_FORMAT string      = "2006-01-02T15-04-05.000"
+	_ROLLING_COMPRESS_SUFFIX    string      = ".gz"
+	_ROLLING_DEFAULT_FILE_MODE  os.FileMode = 0644
+	_ROLLING_DEFAULT_MAX_SIZE   int64       = 100 * 1024 * 1024
+)
+
+type rollingFileWriter struct {
+	handlerName string
+	// exactly one of file and pattern is set
+	file    string
+	pattern string
+	// 0 means no size based rotation
+	maxSize int64
+	// 0 means no time based rotation - otherwise hourly / daily are represented with their length
+	period     time.Duration
+	location   *time.Location
+	maxBackups int
+	maxAge     time.Duration
+	compress   bool
+	fileMode   os.FileMode
+	// TRUE if the mode was configured - then we apply it regardless of the umask
+	forceFileMode  bool
+	reopenOnSignal bool
+
+	lock *sync.Mutex
+	// the file we are writing - nil if not opened yet
+	current     *os.File
+	currentPath string
+	size        int64
+	// the end of the current rotation period
+	periodEnd time.Time
+	// the start of the current rotation period - the file pattern is expanded from this
+	periodStart time.Time
+	// >0 if the period has multiple parts due to the size limit
+	part int
+
+	// serializes the retention runs
+	millLock *sync.Mutex
+	// the retention runs in progress - Close() waits for them
+	millWaitGroup *sync.WaitGroup
+}
+
+func newRollingFileWriter(handlerName string, model RollingFileModel) (*rollingFileWriter, error) {
+	w := &rollingFileWriter{
+		handlerName:    handlerName,
+		reopenOnSignal: model.ReopenOnSignal,
+		fileMode:       _ROLLING_DEFAULT_FILE_MODE,
+		file:           model.File,
+		pattern:        model.FilePattern,
+		maxBackups:     model.MaxBackups,
+		maxAge:         time.Duration(model.MaxAgeDays) * 24 * time.Hour,
+		compress:       model.Compress,
+		location:       time.Local,
+		lock:           new(sync.Mutex),
+		millLock:       new(sync.Mutex),
+
+		millWaitGroup: new(sync.WaitGroup),
+	}
+	if (w.file == "") == (w.pattern == "") {
+		return nil, fmt.Errorf("exactly one of 'file' and 'filePattern' must be given in 'rollingFile'")
+	}
+	if w.pattern != "" {
+		if _, err := expandDatePlaceholders(w.pattern, time.Now()); err != nil {
+			return nil, fmt.Errorf("invalid 'filePattern': %v", err)
+		}
+	}
+	if model.UTC {
+		w.location = time.UTC
+	}
+	if model.FileMode != "" {
+		mode, err := strconv.ParseUint(model.FileMode, 8, 32)
+		if err != nil || mode > 0777 {
+			return nil, fmt.Errorf("invalid 'fileMode' value '%v' - octal permissions like \"0640\" are expected", model.FileMode)
+		}
+		w.fileMode = os.FileMode(mode)
+		w.forceFileMode = true
+	}
+
+	switch strings.ToLower(model.RotateEvery) {
+	case "":
+	case "hourly":
+		w.period = time.Hour
+	case "daily":
+		w.period = 24 * time.Hour
+	default:
+		period, err := time.ParseDuration(model.RotateEvery)
+		if err != nil || period < time.Second {
+			return nil, fmt.Errorf("invalid 'rotateEvery' value '%v' - 'hourly', 'daily' or a duration (at least 1s) is expected", model.RotateEvery)
+		}
+		w.period = period
+	}
+
+	w.maxSize = int64(model.MaxSizeMb) * 1024 * 1024
+	if w.maxSize <= 0 && w.period == 0 && !w.reopenOnSignal {
+		// no rotation was asked at all (and it is not managed externally either) - so we apply the default
+		w.maxSize = _ROLLING_DEFAULT_MAX_SIZE
+	}
+
+	if w.reopenOnSignal {
+		watchReopenSignal(w)
+	}
+	return w, nil
+}
+
+func (w *rollingFileWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	now := time.Now().In(w.location)
+	if w.current == nil {
+		if err := w.openCurrent(now); err != nil {
+			return 0, err
+		}
+	}
+	periodEnded := w.period > 0 && !now.Before(w.periodEnd)
+	sizeExceeded := w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize
+	if periodEnded || sizeExceeded {
+		if err := w.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.current.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *rollingFileWriter) Sync() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.current == nil {
+		return nil
+	}
+	return w.current.Sync()
+}
+
+func (w *rollingFileWriter) Close() error {
+	if w.reopenOnSignal {
+		unwatchReopenSignal(w)
+	}
+	w.lock.Lock()
+	err := w.closeCurrent()
+	w.lock.Unlock()
+	w.millWaitGroup.Wait()
+	return err
+}
+
+// rotates the file right now - regardless of size and time
+func (w *rollingFileWriter) Rotate() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	now := time.Now().In(w.location)
+	if w.current == nil {
+		if err := w.openCurrent(now); err != nil {
+			return err
+		}
+	}
+	return w.rotate(now)
+}
+
+// closes and opens again the current file - so if it was moved away (e.g. by logrotate) then a new file is created
+func (w *rollingFileWriter) reopen() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.current == nil {
+		// will be opened with the next write anyways
+		return nil
+	}
+	path := w.currentPath
+	if err := w.closeCurrent(); err != nil {
+		return err
+	}
+	return w.openFile(path)
+}
+
+// NOT THREAD SAFE! Already assumes Lock is established
+func (w *rollingFileWriter) closeCurrent() error {
+	if w.current == nil {
+		return nil
+	}
+	err := w.current.Close()
+	w.current = nil
+	return err
+}
+
+// opens (or continues) the file of the period the given time falls into
+// NOT THREAD SAFE! Already assumes Lock is established
+func (w *rollingFileWriter) openCurrent(now time.Time) error {
+	w.periodStart, w.periodEnd = w.periodOf(now)
+	w.part = 0
+	path := w.pathOfPart()
+	if w.pattern != "" && w.maxSize > 0 {
+		// after a restart we continue with the last part of the period which is not full yet
+		for {
+			info, err := os.Stat(path)
+			if err != nil || info.Size() < w.maxSize {
+				break
+			}
+			w.part++
+			path = w.pathOfPart()
+		}
+	}
+	if w.pattern == "" && w.period > 0 {
+		// the file might have been written in an earlier period - it must be rotated first
+		if info, err := os.Stat(path); err == nil && info.ModTime().In(w.location).Before(w.periodStart) && info.Size() > 0 {
+			rotatedPath, err := w.renameToBackup(info.ModTime())
+			if err != nil {
+				return err
+			}
+			w.startMill(rotatedPath)
+		}
+	}
+	return w.openFile(path)
+}
+
+// NOT THREAD SAFE! Already assumes Lock is established
+func (w *rollingFileWriter) openFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %v", err)
+	}
+	// note: append mode is important - if the file is truncated externally (copytruncate) then we continue at its new end
+	file, err := openLogFile(path, w.fileMode)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %v", err)
+	}
+	info, err := file.Stat()
+	if err == nil && w.forceFileMode && info.Mode().Perm() != w.fileMode {
+		err = file.Chmod(w.fileMode)
+	}
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open log file: %v", err)
+	}
+	w.current = file
+	w.currentPath = path
+	w.size = info.Size()
+	return nil
+}
+
+// closes the current file and opens the next one - the next period or if we are still in the same period then the next part
+// NOT THREAD SAFE! Already assumes Lock is established
+func (w *rollingFileWriter) rotate(now time.Time) error {
+	rotatedPath := w.currentPath
+	if err := w.closeCurrent(); err != nil {
+		return err
+	}
+
+	periodEnded := w.period > 0 && !now.Before(w.periodEnd)
+	if periodEnded {
+		w.periodStart, w.periodEnd = w.periodOf(now)
+	}
+	if w.pattern != "" {
+		if periodEnded {
+			w.part = 0
+		} else {
+			w.part++
+		}
+	} else {
+		var err error
+		if rotatedPath, err = w.renameToBackup(now); err != nil {
+			// let's continue writing the file at least
+			w.openFile(w.file)
+			return err
+		}
+	}
+	if err := w.openFile(w.pathOfPart()); err != nil {
+		return err
+	}
+	w.startMill(rotatedPath)
+	return nil
+}
+
+// renames the (not opened) file to its backup name - returns the path of the backup or empty string if there was no file
+// NOT THREAD SAFE! Already assumes Lock is established
+func (w *rollingFileWriter) renameToBackup(t time.Time) (string, error) {
+	backupPath := w.backupName(t)
+	if err := os.Rename(w.file, backupPath); err != nil {
+		if os.IsNotExist(err) {
+			// it was moved away externally meanwhile - nothing to do with it
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to rotate log file: %v", err)
+	}
+	return backupPath, nil
+}
+
+// invokes the RotationHooks and applies the retention rules in the background (see mill())
+func (w *rollingFileWriter) startMill(rotatedPath string) {
+	w.millWaitGroup.Add(1)
+	go func() {
+		defer w.millWaitGroup.Done()
+		w.mill(rotatedPath)
+	}()
+}
+
+// returns the start and end of the rotation period the time falls into - periods are aligned to midnight
+func (w *rollingFileWriter) periodOf(t time.Time) (time.Time, time.Time) {
+	if w.period == 0 {
+		return t, time.Time{}
+	}
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, w.location)
+	if w.period == 24*time.Hour {
+		// a calendar day might be shorter or longer due to daylight saving
+		return midnight, midnight.AddDate(0, 0, 1)
+	}
+	start := midnight.Add(t.Sub(midnight) / w.period * w.period)
+	end := start.Add(w.period)
+	if nextMidnight := midnight.AddDate(0, 0, 1); end.After(nextMidnight) {
+		end = nextMidnight
+	}
+	return start, end
+}
+
+// NOT THREAD SAFE! Already assumes Lock is established
+func (w *rollingFileWriter) pathOfPart() string {
+	if w.pattern == "" {
+		return w.file
+	}
+	// we validated the pattern already
+	path, _ := expandDatePlaceholders(w.pattern, w.periodStart)
+	if w.part == 0 {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s.%d%s", strings.TrimSuffix(path, ext), w.part, ext)
+}
+
+// the name of the rotated file - <name>-<timestamp>.<ext> or if there is a backup with the same timestamp already (rotated
+// twice within a millisecond) then <name>-<timestamp>.<counter>.<ext> so we do not overwrite it
+func (w *rollingFileWriter) backupName(t time.Time) string {
+	ext := filepath.Ext(w.file)
+	withoutExt := fmt.Sprintf("%s-%s", strings.TrimSuffix(w.file, ext), t.In(w.location).Format(_ROLLING_BACKUP_TIME_FORMAT))
+	path := withoutExt + ext
+	for counter := 1; backupExists(path); counter++ {
+		path = fmt.Sprintf("%s.%d%s", withoutExt, counter, ext)
+	}
+	return path
+}
+
+// returns TRUE if the backup exists - compressed or not
+func backupExists(path string) bool {
+	for _, candidate := range []string{path, path + _ROLLING_COMPRESS_SUFFIX} {
+		if _, err := os.Lstat(candidate); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// the pattern matching the rotated files (and the current file in case of file pattern) - note: it might match other files too,
+// use parseBackupName() to see if a file is really ours
+func (w *rollingFileWriter) backupGlob() string {
+	if w.pattern != "" {
+		return datePatternToGlob(w.pattern)
+	}
+	ext := filepath.Ext(w.file)
+	return datePatternToGlob(strings.TrimSuffix(w.file, ext)) + "-*" + datePatternToGlob(ext)
+}
+
+// tells if the file is one of our rotated files (compressed or not) - and if so then returns the time it covers (the time of the
+// rotation in case of fixed file name, the end of the period in case of file pattern) and its part number
+func (w *rollingFileWriter) parseBackupName(path string) (time.Time, int, bool) {
+	path = strings.TrimSuffix(filepath.Clean(path), _ROLLING_COMPRESS_SUFFIX)
+
+	if w.pattern == "" {
+		// <name>-<timestamp>.<ext> or <name>-<timestamp>.<counter>.<ext> - in the same directory as the file
+		if filepath.Dir(path) != filepath.Dir(filepath.Clean(w.file)) {
+			return time.Time{}, 0, false
+		}
+		ext := filepath.Ext(w.file)
+		prefix := strings.TrimSuffix(filepath.Base(w.file), ext) + "-"
+		name := filepath.Base(path)
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) || len(name) < len(prefix)+len(ext) {
+			return time.Time{}, 0, false
+		}
+		timestamp := name[len(prefix) : len(name)-len(ext)]
+		if t, err := time.ParseInLocation(_ROLLING_BACKUP_TIME_FORMAT, timestamp, w.location); err == nil {
+			return t, 0, true
+		}
+		// the counter is like the part of the file patterns - the higher the newer
+		counterExt := filepath.Ext(timestamp)
+		counter, err := strconv.Atoi(strings.TrimPrefix(counterExt, "."))
+		if err != nil || counter <= 0 {
+			return time.Time{}, 0, false
+		}
+		t, err := time.ParseInLocation(_ROLLING_BACKUP_TIME_FORMAT, strings.TrimSuffix(timestamp, counterExt), w.location)
+		if err != nil {
+			return time.Time{}, 0, false
+		}
+		return t, counter, true
+	}
+
+	pattern := filepath.Clean(w.pattern)
+	part := 0
+	periodStart, matches := parseDatePlaceholders(pattern, path, w.location)
+	if !matches {
+		// might be a part - <expanded pattern without ext>.<part>.<ext>
+		ext := filepath.Ext(path)
+		withoutExt := strings.TrimSuffix(path, ext)
+		partExt := filepath.Ext(withoutExt)
+		var err error
+		if part, err = strconv.Atoi(strings.TrimPrefix(partExt, ".")); err != nil || part <= 0 {
+			return time.Time{}, 0, false
+		}
+		if periodStart, matches = parseDatePlaceholders(pattern, strings.TrimSuffix(withoutExt, partExt)+ext, w.location); !matches {
+			return time.Time{}, 0, false
+		}
+	}
+	if _, periodEnd := w.periodOf(periodStart); !periodEnd.IsZero() {
+		return periodEnd, part, true
+	}
+	return periodStart, part, true
+}
+
+// invokes the RotationHooks with the just rotated file (if any) then applies the retention rules on the rotated files: removes
+// the ones above maxBackups or older than maxAge and compresses the rest. Only files with names we could have given are touched.
+func (w *rollingFileWriter) mill(rotatedPath string) {
+	w.millLock.Lock()
+	defer w.millLock.Unlock()
+
+	if rotatedPath != "" {
+		invokeRotationHooks(w.handlerName, rotatedPath)
+	}
+
+	w.lock.Lock()
+	currentPath := filepath.Clean(w.currentPath)
+	w.lock.Unlock()
+
+	glob := w.backupGlob()
+	paths, _ := filepath.Glob(glob)
+	compressedPaths, _ := filepath.Glob(glob + _ROLLING_COMPRESS_SUFFIX)
+
+	type backup struct {
+		path string
+		time time.Time
+		part int
+	}
+	backups := []backup{}
+	for _, path := range append(paths, compressedPaths...) {
+		if filepath.Clean(path) == currentPath {
+			continue
+		}
+		t, part, isBackup := w.parseBackupName(path)
+		if !isBackup {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		backups = append(backups, backup{path: path, time: t, part: part})
+	}
+	// newest first
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].time.Equal(backups[j].time) {
+			return backups[i].time.After(backups[j].time)
+		}
+		return backups[i].part > backups[j].part
+	})
+
+	for idx, backup := range backups {
+		tooMany := w.maxBackups > 0 && idx >= w.maxBackups
+		tooOld := w.maxAge > 0 && time.Since(backup.time) > w.maxAge
+		switch {
+		case tooMany || tooOld:
+			os.Remove(backup.path)
+		case w.compress && !strings.HasSuffix(backup.path, _ROLLING_COMPRESS_SUFFIX):
+			if err := compressLogFile(backup.path); err != nil {
+				fmt.Fprintf(os.Stderr, "kt_logging: failed to compress rotated log file %v: %v\n", backup.path, err)
+			}
+		}
+	}
+}
+
+// gzips the file into <path>.gz then removes the original
+func compressLogFile(path string) error {
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	info, err := source.Stat()
+	if err != nil {
+		return err
+	}
+
+	target, err := os.OpenFile(path+_ROLLING_COMPRESS_SUFFIX, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode())
+	if err != nil {
+		return err
+	}
+	gzipWriter := gzip.NewWriter(target)
+	_, err = io.Copy(gzipWriter, source)
+	if err == nil {
+		err = gzipWriter.Close()
+	}
+	if closeErr := target.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path + _ROLLING_COMPRESS_SUFFIX)
+		return err
+	}
+	// keeping the time so retention works the same way on the compressed file
+	os.Chtimes(path+_ROLLING_COMPRESS_SUFFIX, info.ModTime(), info.ModTime())
+	source.Close()
+	return os.Remove(path)
+}
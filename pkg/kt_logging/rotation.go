@@ -0,0 +1,156 @@
+// This file contains the rotation controls of the rolling files: rotating them explicitly, hooks invoked after rotation and
+// reopening them on SIGHUP (for files rotated by an external tool like logrotate)
+
+package kt_logging
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+)
+
+// A Handler can implement this interface if its output can be rotated on demand - see RotateFiles()
+type Rotator interface {
+	// Rotates the output right now
+	Rotate() error
+}
+
+// A RotationHook is invoked after a rolling file was rotated with the path of the rotated file - e.g. to upload it somewhere.
+// It is invoked in the background but before the retention rules (compress, maxBackups, maxAgeDays) touch the file.
+type RotationHook func(handlerName string, rotatedFile string)
+
+// the registered hooks by name - see RegisterRotationHook()
+var rotationHooks = map[string]RotationHook{}
+
+// the registered hooks in order of their names - so they are always invoked in the same order
+var orderedRotationHooks = []RotationHook{}
+var rotationHooksLock = new(sync.RWMutex)
+
+// Registers a hook under the given name which is invoked after every rotation of the rolling files. Registering a new hook with
+// an already used name replaces the former one, registering nil removes it.
+func RegisterRotationHook(name string, hook RotationHook) {
+	rotationHooksLock.Lock()
+	defer rotationHooksLock.Unlock()
+
+	if hook == nil {
+		delete(rotationHooks, name)
+	} else {
+		rotationHooks[name] = hook
+	}
+	names := make([]string, 0, len(rotationHooks))
+	for hookName := range rotationHooks {
+		names = append(names, hookName)
+	}
+	sort.Strings(names)
+	ordered := make([]RotationHook, 0, len(names))
+	for _, hookName := range names {
+		ordered = append(ordered, rotationHooks[hookName])
+	}
+	orderedRotationHooks = ordered
+}
+
+func invokeRotationHooks(handlerName string, rotatedFile string) {
+	rotationHooksLock.RLock()
+	hooks := orderedRotationHooks
+	rotationHooksLock.RUnlock()
+
+	for _, hook := range hooks {
+		hook(handlerName, rotatedFile)
+	}
+}
+
+// Rotates the rolling files of the given handlers right now - or of all handlers if you do not give any names. Handlers which
+// do not write a rolling file are skipped. Errors of the handlers are collected and returned together.
+func RotateFiles(handlerNames ...string) error {
+	loggersLock.RLock()
+	handlers := activeHandlers
+	loggersLock.RUnlock()
+	return handlers.rotate(handlerNames)
+}
+
+// the rolling files to reopen on SIGHUP
+var reopenOnSignalWriters = map[*rollingFileWriter]struct{}{}
+var reopenSignalChannel chan os.Signal
+var reopenOnSignalLock = new(sync.Mutex)
+
+// starts reopening the file of the writer on SIGHUP - we listen to the signal only as long as there is a writer interested in it
+func watchReopenSignal(w *rollingFileWriter) {
+	reopenOnSignalLock.Lock()
+	defer reopenOnSignalLock.Unlock()
+
+	if len(reopenOnSignalWriters) == 0 {
+		reopenSignalChannel = make(chan os.Signal, 1)
+		signal.Notify(reopenSignalChannel, syscall.SIGHUP)
+		go reopenOnSignal(reopenSignalChannel)
+	}
+	reopenOnSignalWriters[w] = struct{}{}
+}
+
+func unwatchReopenSignal(w *rollingFileWriter) {
+	reopenOnSignalLock.Lock()
+	defer reopenOnSignalLock.Unlock()
+
+	if _, found := reopenOnSignalWriters[w]; !found {
+		return
+	}
+	delete(reopenOnSignalWriters, w)
+	if len(reopenOnSignalWriters) == 0 {
+		// after Stop() returns no more signals are delivered so we can close the channel
+		signal.Stop(reopenSignalChannel)
+		close(reopenSignalChannel)
+		reopenSignalChannel = nil
+	}
+}
+
+func reopenOnSignal(signals chan os.Signal) {
+	for range signals {
+		reopenOnSignalLock.Lock()
+		writers := make([]*rollingFileWriter, 0, len(reopenOnSignalWriters))
+		for w := range reopenOnSignalWriters {
+			writers = append(writers, w)
+		}
+		reopenOnSignalLock.Unlock()
+
+		for _, w := range writers {
+			if err := w.reopen(); err != nil {
+				fmt.Fprintf(os.Stderr, "kt_logging: failed to reopen log file of handler '%v': %v\n", w.handlerName, err)
+			}
+		}
+	}
+}
+
+// rotates the given handlers - or all of them if no names are given
+func (hs handlerSet) rotate(handlerNames []string) error {
+	names := handlerNames
+	if len(names) == 0 {
+		for name, handler := range hs.handlers {
+			if _, isRotator := unwrapHandler(handler.handler).(Rotator); isRotator {
+				names = append(names, name)
+			}
+		}
+		// so the errors are coming in a predictable order
+		sort.Strings(names)
+	}
+
+	errs := []error{}
+	for _, name := range names {
+		handler, found := hs.handlers[name]
+		if !found {
+			errs = append(errs, fmt.Errorf("handler '%v' does not exist", name))
+			continue
+		}
+		rotator, isRotator := unwrapHandler(handler.handler).(Rotator)
+		if !isRotator {
+			errs = append(errs, fmt.Errorf("handler '%v' can not be rotated", name))
+			continue
+		}
+		if err := rotator.Rotate(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to rotate handler '%v': %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,175 @@
+// This file contains the 'sampling' option of the handlers - protection against log floods
+//
+// Log events are counted per level + message template within each tick (e.g. 1 second). The first 'initial' events of a kind are
+// written, after that only every 'thereafter'-th. At the end of the tick a summary event is written for each kind which had dropped
+// events - so it is visible what was sampled out and how much.
+
+package kt_logging
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	_DEFAULT_SAMPLING_INITIAL    = 100
+	_DEFAULT_SAMPLING_THEREAFTER = 100
+	_DEFAULT_SAMPLING_TICK       = time.Second
+)
+
+// events of the same kind are sampled together
+type samplingKey struct {
+	level           LogLevel
+	messageTemplate string
+}
+
+type samplingCounter struct {
+	count   int
+	dropped int
+	// the Logger of the last dropped event - the summary goes with this name
+	loggerName string
+}
+
+type samplingHandler struct {
+	name    string
+	handler Handler
+	initial int
+	// 0 means all events are dropped after the initial ones
+	thereafter int
+	tick       time.Duration
+	lock       *sync.Mutex
+	counters   map[samplingKey]*samplingCounter
+	// closed when Close() is invoked - stops the ticker goroutine
+	closing    chan struct{}
+	tickerDone chan struct{}
+	closeOnce  sync.Once
+}
+
+// wraps the Handler so log floods are sampled
+func newSamplingHandler(handlerName string, handler Handler, model SamplingModel) (*samplingHandler, error) {
+	tick := _DEFAULT_SAMPLING_TICK
+	if model.Tick != "" {
+		var err error
+		if tick, err = time.ParseDuration(model.Tick); err != nil || tick <= 0 {
+			return nil, fmt.Errorf("invalid 'tick' value '%v' - positive duration is expected e.g. \"1s\"", model.Tick)
+		}
+	}
+	thereafter := withDefault(model.Thereafter, _DEFAULT_SAMPLING_THEREAFTER)
+	if model.Thereafter < 0 {
+		thereafter = 0
+	}
+
+	h := &samplingHandler{
+		name:       handlerName,
+		handler:    handler,
+		initial:    withDefault(model.Initial, _DEFAULT_SAMPLING_INITIAL),
+		thereafter: thereafter,
+		tick:       tick,
+		lock:       new(sync.Mutex),
+		counters:   map[samplingKey]*samplingCounter{},
+		closing:    make(chan struct{}),
+		tickerDone: make(chan struct{}),
+	}
+	go h.tickLoop()
+	return h, nil
+}
+
+func (h *samplingHandler) Handle(record LogRecord) error {
+	// panic and fatal events are never dropped - they are the last words
+	isLastWords := record.Level == PanicLevel || record.Level == FatalLevel
+	if !isLastWords && !h.sample(record) {
+		return nil
+	}
+	return h.handler.Handle(record)
+}
+
+// returns TRUE if the event should be written
+func (h *samplingHandler) sample(record LogRecord) bool {
+	key := samplingKey{level: record.Level, messageTemplate: record.MessageTemplate}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	counter, found := h.counters[key]
+	if !found {
+		counter = &samplingCounter{}
+		h.counters[key] = counter
+	}
+	counter.count++
+	if counter.count <= h.initial || (h.thereafter > 0 && (counter.count-h.initial)%h.thereafter == 0) {
+		return true
+	}
+	counter.dropped++
+	counter.loggerName = record.LoggerName
+	return false
+}
+
+func (h *samplingHandler) Sync() error {
+	return h.handler.Sync()
+}
+
+// writes the summary of the current tick then closes the wrapped Handler
+func (h *samplingHandler) Close() error {
+	h.closeOnce.Do(func() { close(h.closing) })
+	<-h.tickerDone
+	return h.handler.Close()
+}
+
+func (h *samplingHandler) unwrap() Handler {
+	return h.handler
+}
+
+func (h *samplingHandler) tickLoop() {
+	defer close(h.tickerDone)
+	ticker := time.NewTicker(h.tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			h.writeSummary()
+		case <-h.closing:
+			h.writeSummary()
+			return
+		}
+	}
+}
+
+// starts a new tick - and writes a summary event for each kind of events which had dropped events in the past tick
+func (h *samplingHandler) writeSummary() {
+	h.lock.Lock()
+	counters := h.counters
+	h.counters = map[samplingKey]*samplingCounter{}
+	h.lock.Unlock()
+
+	keys := []samplingKey{}
+	for key, counter := range counters {
+		if counter.dropped > 0 {
+			keys = append(keys, key)
+		}
+	}
+	// so the summaries are coming in a predictable order
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].level != keys[j].level {
+			return keys[i].level < keys[j].level
+		}
+		return keys[i].messageTemplate < keys[j].messageTemplate
+	})
+	for _, key := range keys {
+		counter := counters[key]
+		message := fmt.Sprintf("%d log events were sampled out in the last %v with message: %v", counter.dropped, h.tick, key.messageTemplate)
+		summary := LogRecord{
+			Time:            time.Now(),
+			Level:           key.level,
+			LoggerName:      counter.loggerName,
+			Message:         message,
+			MessageTemplate: message,
+			Labels:          []Label{StringLabel("sampledMessage", key.messageTemplate), IntLabel("sampledOut", int64(counter.dropped))},
+			Context:         context.Background(),
+		}
+		if err := h.handler.Handle(summary); err != nil {
+			reportHandlerError(h.name, err)
+		}
+	}
+}
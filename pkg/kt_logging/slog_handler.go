@@ -0,0 +1,120 @@
+// This file contains a log/slog Handler implementation - so anything logged via slog ends up in a named Logger
+//
+// slog attributes are converted into Labels. Groups are flattened into dotted keys, so e.g. slog.Group("req", "id", 5) becomes
+// the Label "req.id" = 5
+
+package kt_logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+type slogHandler struct {
+	logger *Logger
+	// labels added via WithAttrs() - already flattened
+	labels []Label
+	// the key prefix coming from WithGroup() calls e.g. "req.headers."
+	groupPrefix string
+}
+
+// Returns an slog.Handler which routes slog records into the Logger with the given name. You can use it like
+//
+//	slog.SetDefault(slog.New(kt_logging.NewSlogHandler("thirdparty")))
+func NewSlogHandler(loggerName string) slog.Handler {
+	return &slogHandler{logger: GetLogger(loggerName), labels: []Label{}}
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return !h.logger.isFilteredOut(fromSlogLevel(level)) && h.logger.hasHandlers()
+}
+
+func (h *slogHandler) Handle(ctx context.Context, record slog.Record) error {
+	ctxLabels := allLabelsOfContext(ctx)
+	labels := make([]Label, 0, len(ctxLabels)+len(h.labels)+record.NumAttrs())
+	labels = append(labels, ctxLabels...)
+	labels = append(labels, h.labels...)
+	record.Attrs(func(attr slog.Attr) bool {
+		labels = appendSlogAttr(labels, h.groupPrefix, attr)
+		return true
+	})
+	// the message is not a Printf() style template so we must not resolve it as that
+	h.logger.logMessage(ctx, record.Time, fromSlogLevel(record.Level), labels, record.Message)
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	labels := make([]Label, 0, len(h.labels)+len(attrs))
+	labels = append(labels, h.labels...)
+	for _, attr := range attrs {
+		labels = appendSlogAttr(labels, h.groupPrefix, attr)
+	}
+	return &slogHandler{logger: h.logger, labels: labels, groupPrefix: h.groupPrefix}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{logger: h.logger, labels: h.labels, groupPrefix: h.groupPrefix + name + "."}
+}
+
+// maps slog levels to our levels - custom slog levels are falling into the closest level below them
+func fromSlogLevel(level slog.Level) LogLevel {
+	switch {
+	case level >= slog.LevelError:
+		return ErrorLevel
+	case level >= slog.LevelWarn:
+		return WarningLevel
+	case level >= slog.LevelInfo:
+		return InfoLevel
+	case level >= slog.LevelDebug:
+		return DebugLevel
+	default:
+		return TraceLevel
+	}
+}
+
+// converts the slog attribute into Label(s) and appends them to the given array
+func appendSlogAttr(labels []Label, keyPrefix string, attr slog.Attr) []Label {
+	attr.Value = attr.Value.Resolve()
+	// as slog.Handler contract says: empty attributes are ignored
+	if attr.Equal(slog.Attr{}) {
+		return labels
+	}
+
+	key := keyPrefix + attr.Key
+	switch attr.Value.Kind() {
+	case slog.KindGroup:
+		groupPrefix := keyPrefix
+		if attr.Key != "" {
+			groupPrefix = key + "."
+		}
+		for _, groupAttr := range attr.Value.Group() {
+			labels = appendSlogAttr(labels, groupPrefix, groupAttr)
+		}
+		return labels
+	case slog.KindString:
+		return append(labels, StringLabel(key, attr.Value.String()))
+	case slog.KindInt64:
+		return append(labels, IntLabel(key, attr.Value.Int64()))
+	case slog.KindUint64:
+		return append(labels, IntLabel(key, int64(attr.Value.Uint64())))
+	case slog.KindFloat64:
+		return append(labels, FloatLabel(key, attr.Value.Float64()))
+	case slog.KindBool:
+		return append(labels, BoolLabel(key, attr.Value.Bool()))
+	case slog.KindDuration:
+		return append(labels, StringLabel(key, attr.Value.Duration().String()))
+	case slog.KindTime:
+		return append(labels, StringLabel(key, attr.Value.Time().Format(time.RFC3339Nano)))
+	default:
+		// complex values - as Labels carry atomic values only we go with the string representation
+		return append(labels, StringLabel(key, fmt.Sprint(attr.Value.Any())))
+	}
+}
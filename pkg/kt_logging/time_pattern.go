@@ -0,0 +1,115 @@
+package kt_logging
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// resolves the %Y (year), %m (month), %d (day), %H (hour), %M (minute), %S (second) and %% placeholders in the pattern from the
+// given time - returns error on unknown placeholder. Note: the time is used in its own location, convert it before if needed
+func expandDatePlaceholders(pattern string, t time.Time) (string, error) {
+	if !strings.Contains(pattern, "%") {
+		return pattern, nil
+	}
+	result := strings.Builder{}
+	for idx := 0; idx < len(pattern); idx++ {
+		if pattern[idx] != '%' {
+			result.WriteByte(pattern[idx])
+			continue
+		}
+		idx++
+		if idx >= len(pattern) {
+			return "", fmt.Errorf("placeholder is missing after '%%' at the end")
+		}
+		switch pattern[idx] {
+		case 'Y':
+			fmt.Fprintf(&result, "%04d", t.Year())
+		case 'm':
+			fmt.Fprintf(&result, "%02d", t.Month())
+		case 'd':
+			fmt.Fprintf(&result, "%02d", t.Day())
+		case 'H':
+			fmt.Fprintf(&result, "%02d", t.Hour())
+		case 'M':
+			fmt.Fprintf(&result, "%02d", t.Minute())
+		case 'S':
+			fmt.Fprintf(&result, "%02d", t.Second())
+		case '%':
+			result.WriteByte('%')
+		default:
+			return "", fmt.Errorf("unknown placeholder '%%%c' - %%Y, %%m, %%d, %%H, %%M, %%S or %%%% is supported", pattern[idx])
+		}
+	}
+	return result.String(), nil
+}
+
+// turns the placeholders of the pattern into '*' wildcards - so filepath.Glob() finds everything the pattern can expand to
+func datePatternToGlob(pattern string) string {
+	result := strings.Builder{}
+	for idx := 0; idx < len(pattern); idx++ {
+		switch {
+		case pattern[idx] == '%' && idx+1 < len(pattern) && pattern[idx+1] == '%':
+			result.WriteByte('%')
+			idx++
+		case pattern[idx] == '%':
+			result.WriteByte('*')
+			idx++
+		case pattern[idx] == '*' || pattern[idx] == '?' || pattern[idx] == '[' || pattern[idx] == '\\':
+			// these would be wildcards
+			result.WriteByte('\\')
+			result.WriteByte(pattern[idx])
+		default:
+			result.WriteByte(pattern[idx])
+		}
+	}
+	return result.String()
+}
+
+// the reverse of expandDatePlaceholders() - parses the time back from a value the pattern was expanded to. Returns FALSE if the
+// value is not exactly what the pattern expands to at some point in time. Fields missing from the pattern are the lowest possible.
+func parseDatePlaceholders(pattern string, value string, location *time.Location) (time.Time, bool) {
+	expression := strings.Builder{}
+	expression.WriteString("^")
+	placeholders := []byte{}
+	for idx := 0; idx < len(pattern); idx++ {
+		switch {
+		case pattern[idx] == '%' && idx+1 < len(pattern) && pattern[idx+1] == '%':
+			expression.WriteString("%")
+			idx++
+		case pattern[idx] == '%' && idx+1 < len(pattern) && pattern[idx+1] == 'Y':
+			expression.WriteString(`(\d{4})`)
+			placeholders = append(placeholders, 'Y')
+			idx++
+		case pattern[idx] == '%' && idx+1 < len(pattern):
+			expression.WriteString(`(\d{2})`)
+			placeholders = append(placeholders, pattern[idx+1])
+			idx++
+		default:
+			expression.WriteString(regexp.QuoteMeta(pattern[idx : idx+1]))
+		}
+	}
+	expression.WriteString("$")
+	matcher, err := regexp.Compile(expression.String())
+	if err != nil {
+		return time.Time{}, false
+	}
+	groups := matcher.FindStringSubmatch(value)
+	if groups == nil {
+		return time.Time{}, false
+	}
+
+	fields := map[byte]int{'Y': 1, 'm': 1, 'd': 1}
+	for idx, placeholder := range placeholders {
+		// the regexp guarantees these are numbers
+		fields[placeholder], _ = strconv.Atoi(groups[idx+1])
+	}
+	t := time.Date(fields['Y'], time.Month(fields['m']), fields['d'], fields['H'], fields['M'], fields['S'], 0, location)
+	// e.g. month 13 would be normalized into the next year - that is not something we wrote
+	if expanded, err := expandDatePlaceholders(pattern, t); err != nil || expanded != value {
+		return time.Time{}, false
+	}
+	return t, true
+}
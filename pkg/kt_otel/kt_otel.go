@@ -0,0 +1,80 @@
+// Package kt_otel brings OpenTelemetry trace correlation into kt_logging
+//
+// It lives in a separate package so the core kt_logging package does not depend on OpenTelemetry. Once you invoke Register() all
+// log events fired with a context (see kt_logging.Logger.Ctx() and kt_logging.LogEvent.WithContext()) carry the trace id, span id
+// and trace flags of the span the context holds.
+//
+//	kt_otel.Register(kt_otel.DefaultKeyNames())
+//	...
+//	logger.Ctx(ctx).Info("this event is correlated with the trace")
+package kt_otel
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// the name the extractor is registered with in kt_logging
+const _EXTRACTOR_NAME string = "opentelemetry"
+
+// the key names of the current registration - nil if not registered
+var registeredKeyNames atomic.Pointer[KeyNames]
+
+// The label keys used for the trace correlation labels - so you can match what your log backend expects
+type KeyNames struct {
+	TraceID    string
+	SpanID     string
+	TraceFlags string
+}
+
+// Returns the default label keys: "trace_id", "span_id" and "trace_flags"
+func DefaultKeyNames() KeyNames {
+	return KeyNames{TraceID: "trace_id", SpanID: "span_id", TraceFlags: "trace_flags"}
+}
+
+// Registers the trace correlation into kt_logging - from now on log events fired with a context carrying a valid span get the
+// trace correlation labels with the given keys. Invoking it again replaces the previous registration.
+func Register(keyNames KeyNames) {
+	registeredKeyNames.Store(&keyNames)
+	kt_logging.RegisterContextLabelExtractor(_EXTRACTOR_NAME, func(ctx context.Context) []kt_logging.Label {
+		return TraceLabels(ctx, keyNames)
+	})
+}
+
+// Returns the label keys of the current registration (see Register()) - FALSE if the trace correlation is not registered
+func RegisteredKeyNames() (KeyNames, bool) {
+	keyNames := registeredKeyNames.Load()
+	if keyNames == nil {
+		return KeyNames{}, false
+	}
+	return *keyNames, true
+}
+
+// Removes the trace correlation registered with Register()
+func Unregister() {
+	registeredKeyNames.Store(nil)
+	kt_logging.RegisterContextLabelExtractor(_EXTRACTOR_NAME, nil)
+}
+
+// Returns the trace correlation labels of the span the context holds - or empty array if there is no valid span in the context.
+// Label keys which are empty strings in keyNames are skipped.
+func TraceLabels(ctx context.Context, keyNames KeyNames) []kt_logging.Label {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return []kt_logging.Label{}
+	}
+	labels := make([]kt_logging.Label, 0, 3)
+	if keyNames.TraceID != "" {
+		labels = append(labels, kt_logging.StringLabel(keyNames.TraceID, spanContext.TraceID().String()))
+	}
+	if keyNames.SpanID != "" {
+		labels = append(labels, kt_logging.StringLabel(keyNames.SpanID, spanContext.SpanID().String()))
+	}
+	if keyNames.TraceFlags != "" {
+		labels = append(labels, kt_logging.StringLabel(keyNames.TraceFlags, spanContext.TraceFlags().String()))
+	}
+	return labels
+}
@@ -0,0 +1,427 @@
+// Package kt_otlp brings the "otlp" handler type into kt_logging - exporting log events as OpenTelemetry LogRecords to an OTLP
+// endpoint (e.g. the OpenTelemetry Collector) over HTTP/protobuf or gRPC
+//
+// It lives in a separate package so neither the core kt_logging package nor the trace correlation in kt_otel pulls in the OTLP
+// exporters (and gRPC). The export is done by the OpenTelemetry SDK - so batching, retries and the environment variables
+// (OTEL_EXPORTER_OTLP_...) work the same way as in any OpenTelemetry exporter. Log events are converted this way:
+//
+//   - the Logger name becomes the instrumentation scope
+//
+//   - the level becomes SeverityNumber and SeverityText
+//
+//   - the labels become attributes keeping their types (bool, int, float, string)
+//
+//   - the global labels become resource attributes - they are read on every log event, if they changed (e.g. SetGlobalLabels()
+//     was invoked after the init) then the events logged so far are exported with the previous resource and a new provider is
+//     started with the new resource
+//
+//   - trace id and span id are taken from the span in the context of the event (see kt_logging.Logger.Ctx())
+//
+//     kt_otlp.RegisterOtlpHandlerType()
+//     kt_logging.InitFromConfig("log-config.yaml")
+package kt_otlp
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// the type name of the handler in the config
+	OtlpHandlerType string = "otlp"
+
+	_PROTOCOL_HTTP string = "http/protobuf"
+	_PROTOCOL_GRPC string = "grpc"
+
+	_DEFAULT_HTTP_ENDPOINT string = "http://localhost:4318"
+	_DEFAULT_GRPC_ENDPOINT string = "http://localhost:4317"
+	_HTTP_LOGS_PATH        string = "/v1/logs"
+
+	// how long Sync() and Close() wait for the export at most
+	_FLUSH_TIMEOUT = 30 * time.Second
+)
+
+// The 'settings' of the "otlp" handler type
+type OtlpSettings struct {
+	// "http/protobuf" or "grpc". The default is "http/protobuf".
+	Protocol string `json:"protocol"`
+
+	// URL of the OTLP endpoint e.g. "http://localhost:4318" - "https" scheme means TLS. In case of "http/protobuf" the default
+	// path is "/v1/logs". The default is "http://localhost:4318" for "http/protobuf" and "http://localhost:4317" for "grpc".
+	Endpoint string `json:"endpoint"`
+
+	// Additional headers (or gRPC metadata) e.g. "Authorization"
+	Headers map[string]string `json:"headers"`
+
+	// "gzip" or "none". The default is "none".
+	Compression string `json:"compression"`
+
+	// Timeout of one export in seconds. The default is 10 seconds.
+	TimeoutSec int `json:"timeoutSec"`
+
+	// The maximum number of log events kept in memory waiting for export. The default is 2048.
+	MaxQueueSize int `json:"maxQueueSize"`
+
+	// The maximum number of log events in one export. The default is 512.
+	MaxBatchSize int `json:"maxBatchSize"`
+
+	// Log events are exported at latest after this many milliseconds. The default is 1000.
+	ExportIntervalMs int `json:"exportIntervalMs"`
+}
+
+// Registers the "otlp" handler type in kt_logging - so handlers in the config can use 'type: otlp'. The settings of the handler
+// go into its 'settings' section - see OtlpSettings. Invoke it before kt_logging.InitFromConfig()
+func RegisterOtlpHandlerType() {
+	kt_logging.RegisterHandlerType(OtlpHandlerType, newOtlpHandler)
+}
+
+type otlpHandler struct {
+	settings     OtlpSettings
+	batchOptions []sdklog.BatchProcessorOption
+	// the provider exporting with the current global labels as resource
+	current atomic.Pointer[otlpProvider]
+	// guards switching the provider and the retiring list
+	lock sync.Mutex
+	// the providers replaced because of global label changes - they might be still exporting
+	retiring []*otlpProvider
+}
+
+// a LoggerProvider along with the global labels its resource was built from
+type otlpProvider struct {
+	provider     *sdklog.LoggerProvider
+	globalLabels []kt_logging.Label
+	// kt_logging Logger name -> OpenTelemetry Logger
+	loggers sync.Map
+	// emitting holds the read lock - so the provider is retired only once the emits in flight are done
+	emitLock *sync.RWMutex
+	// once it is TRUE nothing is emitted to the provider anymore
+	retired bool
+	// closed once the provider is shut down after it was retired
+	shutdownDone chan struct{}
+}
+
+// the HandlerFactory of the "otlp" handler type
+func newOtlpHandler(handlerName string, cfg kt_logging.HandlerConfigModel) (kt_logging.Handler, error) {
+	settings := OtlpSettings{}
+	if err := cfg.DecodeSettings(&settings); err != nil {
+		return nil, err
+	}
+
+	batchOptions := []sdklog.BatchProcessorOption{}
+	if settings.MaxQueueSize > 0 {
+		batchOptions = append(batchOptions, sdklog.WithMaxQueueSize(settings.MaxQueueSize))
+	}
+	if settings.MaxBatchSize > 0 {
+		batchOptions = append(batchOptions, sdklog.WithExportMaxBatchSize(settings.MaxBatchSize))
+	}
+	if settings.ExportIntervalMs > 0 {
+		batchOptions = append(batchOptions, sdklog.WithExportInterval(time.Duration(settings.ExportIntervalMs)*time.Millisecond))
+	}
+
+	handler := &otlpHandler{settings: settings, batchOptions: batchOptions}
+	provider, err := handler.newProvider(kt_logging.GetGlobalLabels())
+	if err != nil {
+		return nil, err
+	}
+	handler.current.Store(provider)
+	return handler, nil
+}
+
+// creates a LoggerProvider (with its own exporter) using the given global labels as resource attributes
+func (h *otlpHandler) newProvider(globalLabels []kt_logging.Label) (*otlpProvider, error) {
+	exporter, err := newOtlpExporter(h.settings)
+	if err != nil {
+		return nil, err
+	}
+
+	// the global labels are describing the app - exactly what resource attributes are for
+	resourceAttributes := make([]attribute.KeyValue, 0, len(globalLabels))
+	for _, label := range globalLabels {
+		resourceAttributes = append(resourceAttributes, toResourceAttribute(label))
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(resourceAttributes...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build the resource: %v", err)
+	}
+
+	provider := sdklog.NewLoggerProvider(
+		sdklog.WithResource(res),
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter, h.batchOptions...)),
+	)
+	return &otlpProvider{provider: provider, globalLabels: globalLabels, emitLock: new(sync.RWMutex), shutdownDone: make(chan struct{})}, nil
+}
+
+// returns the provider matching the current global labels with its emitLock read locked - the caller must unlock it once the
+// emit is done
+func (h *otlpHandler) acquireProvider() (*otlpProvider, error) {
+	for {
+		provider, err := h.getProvider()
+		if err != nil {
+			return nil, err
+		}
+		provider.emitLock.RLock()
+		if !provider.retired {
+			return provider, nil
+		}
+		// it was replaced meanwhile - let's take the new one
+		provider.emitLock.RUnlock()
+	}
+}
+
+// returns the provider matching the current global labels - if they changed since the provider was created then the provider
+// is replaced and the replaced one is retired in the background
+func (h *otlpHandler) getProvider() (*otlpProvider, error) {
+	globalLabels := kt_logging.GetGlobalLabels()
+	current := h.current.Load()
+	if sameLabels(current.globalLabels, globalLabels) {
+		return current, nil
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	current = h.current.Load()
+	if sameLabels(current.globalLabels, globalLabels) {
+		// someone else replaced it meanwhile
+		return current, nil
+	}
+	provider, err := h.newProvider(globalLabels)
+	if err != nil {
+		return nil, err
+	}
+	h.current.Store(provider)
+	h.retiring = append(slices.DeleteFunc(h.retiring, (*otlpProvider).isShutDown), current)
+	go current.retire()
+	return provider, nil
+}
+
+// waits for the emits in flight then shuts down the provider (exporting what it still has)
+func (p *otlpProvider) retire() {
+	p.emitLock.Lock()
+	p.retired = true
+	p.emitLock.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), _FLUSH_TIMEOUT)
+	defer cancel()
+	p.provider.Shutdown(ctx)
+	close(p.shutdownDone)
+}
+
+// waits until the replaced providers exported their last events
+func (h *otlpHandler) waitForRetiring() {
+	h.lock.Lock()
+	retiring := slices.Clone(h.retiring)
+	h.lock.Unlock()
+	for _, provider := range retiring {
+		<-provider.shutdownDone
+	}
+
+	// the ones which are shut down already are not needed anymore
+	h.lock.Lock()
+	h.retiring = slices.DeleteFunc(h.retiring, (*otlpProvider).isShutDown)
+	h.lock.Unlock()
+}
+
+func (p *otlpProvider) isShutDown() bool {
+	select {
+	case <-p.shutdownDone:
+		return true
+	default:
+		return false
+	}
+}
+
+func sameLabels(a []kt_logging.Label, b []kt_logging.Label) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func newOtlpExporter(settings OtlpSettings) (sdklog.Exporter, error) {
+	protocol := strings.ToLower(settings.Protocol)
+	if protocol == "" {
+		protocol = _PROTOCOL_HTTP
+	}
+	compression := strings.ToLower(settings.Compression)
+	if compression != "" && compression != "none" && compression != "gzip" {
+		return nil, fmt.Errorf("unknown compression '%v' - 'gzip' or 'none' is supported", settings.Compression)
+	}
+	timeout := time.Duration(settings.TimeoutSec) * time.Second
+
+	switch protocol {
+	case _PROTOCOL_HTTP:
+		endpoint, err := parseEndpoint(settings.Endpoint, _DEFAULT_HTTP_ENDPOINT)
+		if err != nil {
+			return nil, err
+		}
+		if endpoint.Path == "" || endpoint.Path == "/" {
+			endpoint.Path = _HTTP_LOGS_PATH
+		}
+		options := []otlploghttp.Option{otlploghttp.WithEndpointURL(endpoint.String())}
+		if len(settings.Headers) > 0 {
+			options = append(options, otlploghttp.WithHeaders(settings.Headers))
+		}
+		if compression == "gzip" {
+			options = append(options, otlploghttp.WithCompression(otlploghttp.GzipCompression))
+		}
+		if timeout > 0 {
+			options = append(options, otlploghttp.WithTimeout(timeout))
+		}
+		return otlploghttp.New(context.Background(), options...)
+	case _PROTOCOL_GRPC:
+		endpoint, err := parseEndpoint(settings.Endpoint, _DEFAULT_GRPC_ENDPOINT)
+		if err != nil {
+			return nil, err
+		}
+		options := []otlploggrpc.Option{otlploggrpc.WithEndpointURL(endpoint.String())}
+		if len(settings.Headers) > 0 {
+			options = append(options, otlploggrpc.WithHeaders(settings.Headers))
+		}
+		if compression == "gzip" {
+			options = append(options, otlploggrpc.WithCompressor("gzip"))
+		}
+		if timeout > 0 {
+			options = append(options, otlploggrpc.WithTimeout(timeout))
+		}
+		return otlploggrpc.New(context.Background(), options...)
+	default:
+		return nil, fmt.Errorf("unknown protocol '%v' - 'http/protobuf' or 'grpc' is supported", settings.Protocol)
+	}
+}
+
+// parses the endpoint URL - falls back to the default if not given
+func parseEndpoint(endpoint string, defaultEndpoint string) (*url.URL, error) {
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, fmt.Errorf("invalid endpoint '%v' - expecting an URL like %v", endpoint, defaultEndpoint)
+	}
+	return parsed, nil
+}
+
+func (h *otlpHandler) Handle(record kt_logging.LogRecord) error {
+	provider, err := h.acquireProvider()
+	if err != nil {
+		return err
+	}
+	defer provider.emitLock.RUnlock()
+
+	otelRecord := otellog.Record{}
+	otelRecord.SetTimestamp(record.Time)
+	otelRecord.SetObservedTimestamp(record.Time)
+	otelRecord.SetSeverity(toOtelSeverity(record.Level))
+	otelRecord.SetSeverityText(strings.ToUpper(record.Level.String()))
+	otelRecord.SetBody(otellog.StringValue(record.Message))
+
+	// trace correlation labels (see kt_otel.Register()) are redundant - the trace context goes into the dedicated fields
+	skippedKeys := map[string]bool{}
+	if trace.SpanContextFromContext(record.Context).IsValid() {
+		if keyNames, isRegistered := kt_otel.RegisteredKeyNames(); isRegistered {
+			skippedKeys = map[string]bool{keyNames.TraceID: true, keyNames.SpanID: true, keyNames.TraceFlags: true}
+		}
+	}
+	attributes := make([]otellog.KeyValue, 0, len(record.Labels))
+	for _, label := range record.Labels {
+		if !skippedKeys[label.GetKey()] {
+			attributes = append(attributes, toLogAttribute(label))
+		}
+	}
+	otelRecord.AddAttributes(attributes...)
+
+	// the SDK takes the trace context from the context
+	provider.getLogger(record.LoggerName).Emit(record.Context, otelRecord)
+	return nil
+}
+
+// returns the OpenTelemetry Logger - the instrumentation scope is the name of our Logger
+func (p *otlpProvider) getLogger(loggerName string) otellog.Logger {
+	if logger, contains := p.loggers.Load(loggerName); contains {
+		return logger.(otellog.Logger)
+	}
+	logger, _ := p.loggers.LoadOrStore(loggerName, p.provider.Logger(loggerName))
+	return logger.(otellog.Logger)
+}
+
+func (h *otlpHandler) Sync() error {
+	// the replaced providers are exporting their last events
+	h.waitForRetiring()
+	ctx, cancel := context.WithTimeout(context.Background(), _FLUSH_TIMEOUT)
+	defer cancel()
+	return h.current.Load().provider.ForceFlush(ctx)
+}
+
+func (h *otlpHandler) Close() error {
+	h.waitForRetiring()
+	ctx, cancel := context.WithTimeout(context.Background(), _FLUSH_TIMEOUT)
+	defer cancel()
+	return h.current.Load().provider.Shutdown(ctx)
+}
+
+// maps our levels to OpenTelemetry severities
+func toOtelSeverity(level kt_logging.LogLevel) otellog.Severity {
+	switch level {
+	case kt_logging.TraceLevel:
+		return otellog.SeverityTrace
+	case kt_logging.DebugLevel:
+		return otellog.SeverityDebug
+	case kt_logging.InfoLevel:
+		return otellog.SeverityInfo
+	case kt_logging.WarningLevel:
+		return otellog.SeverityWarn
+	case kt_logging.ErrorLevel:
+		return otellog.SeverityError
+	case kt_logging.PanicLevel, kt_logging.FatalLevel:
+		return otellog.SeverityFatal
+	default:
+		return otellog.SeverityUndefined
+	}
+}
+
+func toLogAttribute(label kt_logging.Label) otellog.KeyValue {
+	switch label.GetType() {
+	case kt_logging.BoolType:
+		return otellog.Bool(label.GetKey(), label.GetBoolValue())
+	case kt_logging.IntType:
+		return otellog.Int64(label.GetKey(), label.GetIntValue())
+	case kt_logging.FloatType:
+		return otellog.Float64(label.GetKey(), label.GetFloatValue())
+	default:
+		return otellog.String(label.GetKey(), label.GetStringValue())
+	}
+}
+
+func toResourceAttribute(label kt_logging.Label) attribute.KeyValue {
+	switch label.GetType() {
+	case kt_logging.BoolType:
+		return attribute.Bool(label.GetKey(), label.GetBoolValue())
+	case kt_logging.IntType:
+		return attribute.Int64(label.GetKey(), label.GetIntValue())
+	case kt_logging.FloatType:
+		return attribute.Float64(label.GetKey(), label.GetFloatValue())
+	default:
+		return attribute.String(label.GetKey(), label.GetStringValue())
+	}
+}
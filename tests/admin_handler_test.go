@@ -0,0 +1,126 @@
+package kt_logging_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+)
+
+func TestAdminHandlerListsLoggers(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "log-config.yaml")
+	writeReloadTestConfig(t, cfgPath, "warning")
+	if err := kt_logging.InitFromConfig(cfgPath); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	kt_logging.GetLogger("controller.something")
+
+	server := httptest.NewServer(kt_logging.AdminHandler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var loggers []kt_logging.AdminLoggerModel
+	if err := json.NewDecoder(resp.Body).Decode(&loggers); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	found := false
+	for _, logger := range loggers {
+		if logger.Name == "controller.something" {
+			found = true
+			if logger.Level != "warning" || !logger.InheritsLevel || len(logger.Handlers) != 1 || logger.Handlers[0] != "discard" {
+				t.Fatalf("unexpected logger entry: %+v", logger)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("'controller.something' is missing from the response: %+v", loggers)
+	}
+}
+
+func TestAdminHandlerChangesLevelWithTTL(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "log-config.yaml")
+	writeReloadTestConfig(t, cfgPath, "warning")
+	if err := kt_logging.InitFromConfig(cfgPath); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	child := kt_logging.GetLogger("controller.something")
+
+	server := httptest.NewServer(kt_logging.AdminHandler())
+	defer server.Close()
+
+	body := `{"logger": "controller.something", "level": "debug", "ttl": "50ms"}`
+	req, _ := http.NewRequest(http.MethodPut, server.URL, strings.NewReader(body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %v", resp.StatusCode)
+	}
+	if child.GetLevel() != kt_logging.DebugLevel {
+		t.Fatalf("expected debug level, got %v", child.GetLevel())
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for child.GetLevel() != kt_logging.WarningLevel {
+		if time.Now().After(deadline) {
+			t.Fatalf("level was not restored after ttl, still %v", child.GetLevel())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// it should follow its parent again
+	kt_logging.SetLevel("controller", kt_logging.ErrorLevel)
+	if child.GetLevel() != kt_logging.ErrorLevel {
+		t.Fatalf("restored logger should inherit the level of its parent again, got %v", child.GetLevel())
+	}
+}
+
+func TestAdminHandlerRejectsInvalidLevel(t *testing.T) {
+	handler := kt_logging.AdminHandler()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"logger": "controller", "level": "chatty"}`))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("expected bad request, got %v", recorder.Code)
+	}
+}
+
+func TestAdminHandlerOverriddenTTLIsNotCutShort(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "log-config.yaml")
+	writeReloadTestConfig(t, cfgPath, "warning")
+	if err := kt_logging.InitFromConfig(cfgPath); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	handler := kt_logging.AdminHandler()
+	change := func(body string) {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body)))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("unexpected status %v", recorder.Code)
+		}
+	}
+
+	// the first timer fires right around the override - the override must win anyways
+	for i := 0; i < 50; i++ {
+		change(`{"logger": "controller", "level": "debug", "ttl": "1ms"}`)
+		time.Sleep(time.Millisecond)
+		change(`{"logger": "controller", "level": "error", "ttl": "1h"}`)
+		time.Sleep(5 * time.Millisecond)
+		if level := kt_logging.GetLogger("controller").GetLevel(); level != kt_logging.ErrorLevel {
+			t.Fatalf("the overriding change was cut short - level is %v", level)
+		}
+		// back to the original for the next round
+		change(`{"logger": "controller", "level": "warning"}`)
+	}
+}
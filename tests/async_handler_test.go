@@ -0,0 +1,108 @@
+package kt_logging_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+)
+
+// a capture handler which can be held up - like a slow disk
+type gatedHandler struct {
+	captureHandler
+	// Handle() waits until it can receive from here
+	gate chan struct{}
+}
+
+func (h *gatedHandler) Handle(record kt_logging.LogRecord) error {
+	<-h.gate
+	return h.captureHandler.Handle(record)
+}
+
+// initializes the logging with one gated handler wrapped with the given async config
+func initWithGatedHandler(t *testing.T, async *kt_logging.AsyncModel) *gatedHandler {
+	handler := &gatedHandler{gate: make(chan struct{})}
+	kt_logging.RegisterHandlerType("gated", func(handlerName string, cfg kt_logging.HandlerConfigModel) (kt_logging.Handler, error) {
+		return handler, nil
+	})
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{Type: "gated", Level: "debug", Async: async})
+	return handler
+}
+
+func TestAsyncHandlerDoesNotBlockTheCaller(t *testing.T) {
+	handler := initWithGatedHandler(t, &kt_logging.AsyncModel{QueueSize: 100})
+	logger := kt_logging.GetLogger("async")
+
+	logged := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			logger.Info("event %d", i)
+		}
+		close(logged)
+	}()
+	select {
+	case <-logged:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("logging was blocked by the handler")
+	}
+
+	// let the handler go - Sync must wait until everything is written
+	close(handler.gate)
+	if err := kt_logging.Sync(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	handler.lock.Lock()
+	defer handler.lock.Unlock()
+	if len(handler.records) != 10 {
+		t.Fatalf("expected 10 records after sync but got %d", len(handler.records))
+	}
+	for i, record := range handler.records {
+		if record.Message != fmt.Sprintf("event %d", i) {
+			t.Errorf("records are out of order: %v at %d", record.Message, i)
+		}
+	}
+}
+
+func TestAsyncHandlerCountsDroppedEvents(t *testing.T) {
+	handler := initWithGatedHandler(t, &kt_logging.AsyncModel{QueueSize: 5, Overflow: "dropNewest"})
+	logger := kt_logging.GetLogger("async")
+
+	// the worker takes the first one and gets stuck - 5 fit into the queue, the rest is dropped
+	logger.Info("event 0")
+	time.Sleep(50 * time.Millisecond)
+	for i := 1; i < 20; i++ {
+		logger.Info("event %d", i)
+	}
+	if dropped := kt_logging.GetDroppedEventCount("handler"); dropped != 14 {
+		t.Errorf("expected 14 dropped events but got %d", dropped)
+	}
+
+	// shutdown drains the queue
+	close(handler.gate)
+	if err := kt_logging.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	handler.lock.Lock()
+	defer handler.lock.Unlock()
+	if len(handler.records) != 6 || handler.records[5].Message != "event 5" || !handler.isClosed {
+		t.Errorf("expected the first 6 events to be written and the handler closed but got %d records", len(handler.records))
+	}
+}
+
+func TestAsyncHandlerRejectsInvalidOverflow(t *testing.T) {
+	kt_logging.RegisterHandlerType("gated", func(handlerName string, cfg kt_logging.HandlerConfigModel) (kt_logging.Handler, error) {
+		return &gatedHandler{}, nil
+	})
+	cfgPath := t.TempDir() + "/log-config.json"
+	writeJsonConfig(t, cfgPath, kt_logging.ConfigModel{
+		Loggers: map[string]kt_logging.LoggerConfigModel{"root": {Level: "debug", HandlerNames: []string{"handler"}}},
+		Handlers: map[string]kt_logging.HandlerConfigModel{
+			"handler": {Type: "gated", Async: &kt_logging.AsyncModel{Overflow: "explode"}},
+		},
+	})
+	if err := kt_logging.InitFromConfig(cfgPath); err == nil {
+		t.Errorf("init should fail with invalid overflow policy")
+	}
+}
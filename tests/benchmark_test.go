@@ -1,7 +1,12 @@
 package kt_logging_test
 
 import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
 )
@@ -24,3 +29,59 @@ func BenchmarkLogging(b *testing.B) {
 
 	b.StopTimer()
 }
+
+// a handler as slow as a struggling disk
+type slowHandler struct{}
+
+func (h slowHandler) Handle(record kt_logging.LogRecord) error {
+	time.Sleep(20 * time.Microsecond)
+	return nil
+}
+
+func (h slowHandler) Sync() error {
+	return nil
+}
+
+func (h slowHandler) Close() error {
+	return nil
+}
+
+// compares the latency the logging goroutine sees with a slow handler - written synchronously vs. via the async queue. With
+// 'block' overflow every event is written, so in the long run the logging can not be faster than the handler. With 'dropNewest'
+// the logging goroutine never waits - the share of the dropped events is reported as dropped/op.
+func BenchmarkSlowHandler(b *testing.B) {
+	kt_logging.RegisterHandlerType("slow", func(handlerName string, cfg kt_logging.HandlerConfigModel) (kt_logging.Handler, error) {
+		return slowHandler{}, nil
+	})
+
+	for _, benchmark := range []struct {
+		name  string
+		async *kt_logging.AsyncModel
+	}{
+		{name: "sync"},
+		{name: "async", async: &kt_logging.AsyncModel{QueueSize: 1000, Overflow: "block"}},
+		{name: "asyncDropNewest", async: &kt_logging.AsyncModel{QueueSize: 1000, Overflow: "dropNewest"}},
+	} {
+		b.Run(benchmark.name, func(b *testing.B) {
+			cfgPath := filepath.Join(b.TempDir(), "log-config.json")
+			content, _ := json.Marshal(kt_logging.ConfigModel{
+				Loggers:  map[string]kt_logging.LoggerConfigModel{"root": {Level: "info", HandlerNames: []string{"slow"}}},
+				Handlers: map[string]kt_logging.HandlerConfigModel{"slow": {Type: "slow", Level: "info", Async: benchmark.async}},
+			})
+			os.WriteFile(cfgPath, content, 0644)
+			if err := kt_logging.InitFromConfig(cfgPath); err != nil {
+				b.Fatalf("init failed: %v", err)
+			}
+			logger := kt_logging.GetLogger("main")
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				logger.WithLabel(kt_logging.StringLabel("key", "value")).Info("hello")
+			}
+			b.StopTimer()
+
+			b.ReportMetric(float64(kt_logging.GetDroppedEventCount("slow"))/float64(b.N), "dropped/op")
+			kt_logging.Shutdown(context.Background())
+		})
+	}
+}
@@ -0,0 +1,68 @@
+//go:build linux
+
+package kt_logging_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+)
+
+func TestReloadConfigOnSignal(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "log-config.yaml")
+	writeReloadTestConfig(t, cfgPath, "warning")
+	if err := kt_logging.InitFromConfig(cfgPath); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	stop := kt_logging.ReloadConfigOnSignal(cfgPath)
+	defer stop()
+
+	writeReloadTestConfig(t, cfgPath, "error")
+	syscall.Kill(os.Getpid(), syscall.SIGHUP)
+
+	logger := kt_logging.GetLogger("controller")
+	deadline := time.Now().Add(2 * time.Second)
+	for logger.GetLevel() != kt_logging.ErrorLevel {
+		if time.Now().After(deadline) {
+			t.Fatalf("config was not reloaded on signal, level is still %v", logger.GetLevel())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatchConfigDoesNotReloadOnSignal(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "log-config.json")
+	writeConfig := func(level string) {
+		writeJsonConfig(t, cfgPath, kt_logging.ConfigModel{
+			Loggers: map[string]kt_logging.LoggerConfigModel{"root": {Level: level, HandlerNames: []string{"file"}}},
+			Handlers: map[string]kt_logging.HandlerConfigModel{"file": {
+				Level:       "debug",
+				RollingFile: &kt_logging.RollingFileModel{File: filepath.Join(dir, "app.log"), ReopenOnSignal: true},
+			}},
+		})
+	}
+	writeConfig("warning")
+	if err := kt_logging.InitFromConfig(cfgPath); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	defer kt_logging.Shutdown(context.Background())
+	stop, err := kt_logging.WatchConfig(cfgPath, time.Hour)
+	if err != nil {
+		t.Fatalf("watch failed: %v", err)
+	}
+	defer stop()
+
+	// the SIGHUP of logrotate is meant for the rolling file only
+	writeConfig("debug")
+	syscall.Kill(os.Getpid(), syscall.SIGHUP)
+	time.Sleep(200 * time.Millisecond)
+	if level := kt_logging.GetLogger("root").GetLevel(); level != kt_logging.WarningLevel {
+		t.Errorf("config must not be reloaded on signal without opt-in, level is %v", level)
+	}
+}
@@ -0,0 +1,223 @@
+package kt_logging_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+)
+
+const reloadTestConfigTemplate = `
+loggers:
+  root:
+    level: info
+    handlers:
+      - discard
+  controller:
+    level: %v
+    handlers:
+      - discard
+handlers:
+  discard:
+    level: debug
+    encoding: json
+    outputPaths:
+`
+
+func writeReloadTestConfig(t *testing.T, cfgPath string, controllerLevel string) {
+	writeFile(t, cfgPath, fmt.Sprintf(reloadTestConfigTemplate, controllerLevel))
+}
+
+func TestReloadFromConfigKeepsLoggerInstances(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "log-config.yaml")
+	writeReloadTestConfig(t, cfgPath, "warning")
+	if err := kt_logging.InitFromConfig(cfgPath); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+
+	configured := kt_logging.GetLogger("controller")
+	derived := kt_logging.GetLogger("controller.something")
+	if derived.GetLevel() != kt_logging.WarningLevel {
+		t.Fatalf("expected derived logger on warning level, got %v", derived.GetLevel())
+	}
+
+	writeReloadTestConfig(t, cfgPath, "debug")
+	if err := kt_logging.ReloadFromConfig(cfgPath); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+
+	if kt_logging.GetLogger("controller") != configured || kt_logging.GetLogger("controller.something") != derived {
+		t.Fatalf("reload must keep the already handed out Logger instances")
+	}
+	if configured.GetLevel() != kt_logging.DebugLevel || derived.GetLevel() != kt_logging.DebugLevel {
+		t.Fatalf("expected loggers on debug level after reload, got %v and %v", configured.GetLevel(), derived.GetLevel())
+	}
+}
+
+func TestReloadFromConfigKeepsSetupOnError(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "log-config.yaml")
+	writeReloadTestConfig(t, cfgPath, "warning")
+	if err := kt_logging.InitFromConfig(cfgPath); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+
+	writeReloadTestConfig(t, cfgPath, "chatty")
+	if err := kt_logging.ReloadFromConfig(cfgPath); err == nil {
+		t.Fatalf("expected reload to fail on invalid level")
+	}
+	if kt_logging.GetLogger("controller").GetLevel() != kt_logging.WarningLevel {
+		t.Fatalf("failed reload must not change the current setup")
+	}
+}
+
+func TestWatchConfigPicksUpChanges(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "log-config.yaml")
+	writeReloadTestConfig(t, cfgPath, "warning")
+	if err := kt_logging.InitFromConfig(cfgPath); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	stop, err := kt_logging.WatchConfig(cfgPath, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("watch failed: %v", err)
+	}
+	defer stop()
+
+	logger := kt_logging.GetLogger("controller")
+	// let's make sure the modification time differs even on file systems with coarse timestamps
+	time.Sleep(20 * time.Millisecond)
+	writeReloadTestConfig(t, cfgPath, "error")
+	os.Chtimes(cfgPath, time.Now().Add(time.Second), time.Now().Add(time.Second))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for logger.GetLevel() != kt_logging.ErrorLevel {
+		if time.Now().After(deadline) {
+			t.Fatalf("config change was not picked up, level is still %v", logger.GetLevel())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestShutdownMakesLoggingNoop(t *testing.T) {
+	logPath := initWithJsonFileOutput(t)
+	logger := kt_logging.GetLogger("lifecycle")
+	logger.Info("before shutdown")
+	if err := kt_logging.Sync(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	if err := kt_logging.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	logger.Info("after shutdown")
+	kt_logging.GetLogger("lifecycle.new").Info("after shutdown from a new logger")
+	if !logger.IsSilent() {
+		t.Errorf("logger should be silent after shutdown")
+	}
+
+	events := readJsonLogEvents(t, logPath)
+	if len(events) != 1 || events[0]["message"] != "before shutdown" {
+		t.Fatalf("expected only the event before shutdown, got %v", events)
+	}
+}
+
+func TestReloadStopsInheritingLevelOfNewlyConfiguredLogger(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "log-config.yaml")
+	writeReloadTestConfig(t, cfgPath, "warning")
+	if err := kt_logging.InitFromConfig(cfgPath); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	// derived from root so far
+	logger := kt_logging.GetLogger("a.b")
+
+	writeFile(t, cfgPath, reloadTestConfigWithLogger("a.b", "info"))
+	if err := kt_logging.ReloadFromConfig(cfgPath); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	kt_logging.GetLogger("root").SetLevel(kt_logging.DebugLevel)
+	if logger.GetLevel() != kt_logging.InfoLevel {
+		t.Errorf("explicitly configured logger must not follow its parent, level is %v", logger.GetLevel())
+	}
+}
+
+func reloadTestConfigWithLogger(loggerName string, level string) string {
+	return fmt.Sprintf(`
+loggers:
+  root:
+    level: info
+    handlers:
+      - discard
+  %v:
+    level: %v
+    handlers:
+      - discard
+handlers:
+  discard:
+    level: debug
+    encoding: json
+    outputPaths:
+`, loggerName, level)
+}
+
+// a handler which notices if it is closed while it is still handling an event
+type closeTrackingHandler struct {
+	entered chan struct{}
+	gate    chan struct{}
+	closed  atomic.Bool
+	// TRUE if Close() was invoked while an event was being handled
+	closedInFlight atomic.Bool
+}
+
+func (h *closeTrackingHandler) Handle(record kt_logging.LogRecord) error {
+	h.entered <- struct{}{}
+	<-h.gate
+	if h.closed.Load() {
+		h.closedInFlight.Store(true)
+	}
+	return nil
+}
+
+func (h *closeTrackingHandler) Sync() error {
+	return nil
+}
+
+func (h *closeTrackingHandler) Close() error {
+	h.closed.Store(true)
+	return nil
+}
+
+func TestReloadClosesPreviousHandlersOnlyAfterEventsInFlight(t *testing.T) {
+	handler := &closeTrackingHandler{entered: make(chan struct{}, 1), gate: make(chan struct{})}
+	kt_logging.RegisterHandlerType("closeTracking", func(handlerName string, cfg kt_logging.HandlerConfigModel) (kt_logging.Handler, error) {
+		return handler, nil
+	})
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{Type: "closeTracking", Level: "debug"})
+
+	logged := make(chan struct{})
+	go func() {
+		kt_logging.GetLogger("app").Info("in flight")
+		close(logged)
+	}()
+	<-handler.entered
+
+	reloaded := make(chan struct{})
+	go func() {
+		initWithSingleHandler(t, kt_logging.HandlerConfigModel{Type: "memory", Level: "debug"})
+		close(reloaded)
+	}()
+	// the reload waits for the event in flight
+	time.Sleep(50 * time.Millisecond)
+	if handler.closed.Load() {
+		t.Errorf("the handler was closed while an event was in flight")
+	}
+	close(handler.gate)
+	<-logged
+	<-reloaded
+	if !handler.closed.Load() || handler.closedInFlight.Load() {
+		t.Errorf("the previous handler should be closed after the event in flight")
+	}
+}
@@ -0,0 +1,30 @@
+package kt_logging_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+)
+
+func TestContextLabelsAreStackedAndMerged(t *testing.T) {
+	logPath := initWithJsonFileOutput(t)
+
+	ctx := kt_logging.ContextWithLabels(context.Background(), kt_logging.StringLabel("requestId", "r1"), kt_logging.StringLabel("tenantId", "t1"))
+	nestedCtx := kt_logging.ContextWithLabels(ctx, kt_logging.StringLabel("tenantId", "t2"), kt_logging.IntLabel("step", 2))
+
+	kt_logging.GetLogger("ctx").Ctx(nestedCtx).WithLabel(kt_logging.BoolLabel("own", true)).Info("nested")
+	kt_logging.GetLogger("ctx").WithLabel(kt_logging.BoolLabel("own", true)).WithContext(ctx).Info("outer")
+
+	events := readJsonLogEvents(t, logPath)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %v", events)
+	}
+	nested, outer := events[0], events[1]
+	if nested["requestId"] != "r1" || nested["tenantId"] != "t2" || nested["step"] != float64(2) || nested["own"] != true {
+		t.Errorf("unexpected labels on nested event: %v", nested)
+	}
+	if outer["requestId"] != "r1" || outer["tenantId"] != "t1" || outer["step"] != nil || outer["own"] != true {
+		t.Errorf("unexpected labels on outer event: %v", outer)
+	}
+}
@@ -0,0 +1,185 @@
+package kt_logging_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+)
+
+// inits with a memory handler behind a root Logger with the given filters
+func initWithFilteredLogger(t *testing.T, dedup *kt_logging.DedupModel, rateLimit *kt_logging.RateLimitModel) {
+	cfgPath := filepath.Join(t.TempDir(), "log-config.json")
+	writeJsonConfig(t, cfgPath, kt_logging.ConfigModel{
+		Loggers: map[string]kt_logging.LoggerConfigModel{
+			"root": {Level: "debug", HandlerNames: []string{"handler"}, Dedup: dedup, RateLimit: rateLimit},
+		},
+		Handlers: map[string]kt_logging.HandlerConfigModel{"handler": {Type: "memory", Level: "debug"}},
+	})
+	if err := kt_logging.InitFromConfig(cfgPath); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+}
+
+func labelOf(record kt_logging.LogRecord, key string) (kt_logging.Label, bool) {
+	for _, label := range record.Labels {
+		if label.GetKey() == key {
+			return label, true
+		}
+	}
+	return kt_logging.Label{}, false
+}
+
+// waits until the memory handler has the given number of events
+func waitForEvents(t *testing.T, count int) []kt_logging.LogRecord {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		records, err := kt_logging.RecentEvents("handler", kt_logging.RecentEventsFilter{})
+		if err != nil {
+			t.Fatalf("failed to get recent events: %v", err)
+		}
+		if len(records) >= count || time.Now().After(deadline) {
+			return records
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLoggerDedupWritesSummaryWhenWindowCloses(t *testing.T) {
+	initWithFilteredLogger(t, &kt_logging.DedupModel{Window: "100ms"}, nil)
+	logger := kt_logging.GetLogger("app")
+
+	for i := 0; i < 5; i++ {
+		logger.WithLabel(kt_logging.StringLabel("user", "john")).Warn("disk is full on %s", "/data")
+	}
+	logger.Warn("something else")
+	if got := recentMessages(t); got != "disk is full on /data,something else" {
+		t.Fatalf("repetitions should be held back but got %v", got)
+	}
+
+	records := waitForEvents(t, 3)
+	if len(records) != 3 {
+		t.Fatalf("expected the summary event but got %v", messagesOf(records))
+	}
+	summary := records[2]
+	if summary.Message != "disk is full on /data" || summary.Level != kt_logging.WarningLevel {
+		t.Errorf("unexpected summary event: %v %v", summary.Level, summary.Message)
+	}
+	if repeated, found := labelOf(summary, "repeated"); !found || repeated.GetIntValue() != 4 {
+		t.Errorf("expected 'repeated' 4 but got %v", repeated.GetIntValue())
+	}
+	if _, found := labelOf(summary, "user"); !found {
+		t.Errorf("the labels of the event should be kept in the summary")
+	}
+	for _, key := range []string{"firstTime", "lastTime"} {
+		if label, found := labelOf(summary, key); !found || label.GetStringValue() == "" {
+			t.Errorf("missing label '%v' in summary", key)
+		}
+	}
+}
+
+func TestLoggerDedupIsFlushedOnReload(t *testing.T) {
+	initWithFilteredLogger(t, &kt_logging.DedupModel{Window: "1h"}, nil)
+	logger := kt_logging.GetLogger("app")
+	logger.Info("repeated")
+	logger.Info("repeated")
+	logger.Info("repeated")
+
+	records, err := kt_logging.RecentEvents("handler", kt_logging.RecentEventsFilter{})
+	if err != nil {
+		t.Fatalf("failed to get recent events: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected only the first event but got %v", messagesOf(records))
+	}
+	// the held back repetitions go to the handlers of the new config
+	initWithFilteredLogger(t, nil, nil)
+	if got := recentMessages(t); got != "repeated" {
+		t.Fatalf("expected the summary in the new handler but got %v", got)
+	}
+}
+
+func TestHandlerDedup(t *testing.T) {
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Type:  "memory",
+		Level: "debug",
+		Dedup: &kt_logging.DedupModel{Window: "100ms"},
+	})
+	logger := kt_logging.GetLogger("app")
+	for i := 0; i < 3; i++ {
+		logger.Info("connection lost")
+	}
+	// different labels - not identical
+	logger.WithLabel(kt_logging.IntLabel("attempt", 2)).Info("connection lost")
+
+	records := waitForEvents(t, 3)
+	if len(records) != 3 {
+		t.Fatalf("unexpected events: %v", messagesOf(records))
+	}
+	if repeated, found := labelOf(records[2], "repeated"); !found || repeated.GetIntValue() != 2 {
+		t.Errorf("expected 'repeated' 2 but got %v", repeated.GetIntValue())
+	}
+}
+
+func TestLoggerRateLimit(t *testing.T) {
+	initWithFilteredLogger(t, nil, &kt_logging.RateLimitModel{EventsPerSec: 10, Burst: 2})
+	logger := kt_logging.GetLogger("app")
+	for i := 0; i < 5; i++ {
+		logger.Info("event %d", i)
+	}
+	if got := recentMessages(t); got != "event 0,event 1" {
+		t.Fatalf("unexpected events within the burst: %v", got)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	logger.Info("later")
+	records := waitForEvents(t, 4)
+	if len(records) != 4 {
+		t.Fatalf("unexpected events: %v", messagesOf(records))
+	}
+	notice := records[2]
+	if dropped, found := labelOf(notice, "rateLimited"); !found || dropped.GetIntValue() != 3 || notice.Level != kt_logging.WarningLevel {
+		t.Errorf("unexpected notice about the dropped events: %v %v", notice.Level, notice.Message)
+	}
+	if records[3].Message != "later" {
+		t.Errorf("expected the event after the notice but got %v", records[3].Message)
+	}
+}
+
+func TestLoggerRateLimitReportsDropsWithoutFollowingEvent(t *testing.T) {
+	initWithFilteredLogger(t, nil, &kt_logging.RateLimitModel{EventsPerSec: 1, Burst: 1})
+	logger := kt_logging.GetLogger("app")
+	for i := 0; i < 4; i++ {
+		logger.Info("event %d", i)
+	}
+
+	// nothing is logged after the flood - the report comes anyways
+	records := waitForEvents(t, 2)
+	if len(records) != 2 {
+		t.Fatalf("expected the report about the dropped events but got %v", messagesOf(records))
+	}
+	if dropped, found := labelOf(records[1], "rateLimited"); !found || dropped.GetIntValue() != 3 {
+		t.Errorf("unexpected report about the dropped events: %v", records[1].Message)
+	}
+}
+
+func TestLoggerRateLimitReportsDropsOnReload(t *testing.T) {
+	initWithFilteredLogger(t, nil, &kt_logging.RateLimitModel{EventsPerSec: 1, Burst: 1})
+	logger := kt_logging.GetLogger("app")
+	for i := 0; i < 4; i++ {
+		logger.Info("event %d", i)
+	}
+
+	initWithFilteredLogger(t, nil, nil)
+	records, err := kt_logging.RecentEvents("handler", kt_logging.RecentEventsFilter{})
+	if err != nil {
+		t.Fatalf("failed to get recent events: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected the report in the new handler but got %v", messagesOf(records))
+	}
+	if dropped, found := labelOf(records[0], "rateLimited"); !found || dropped.GetIntValue() != 3 {
+		t.Errorf("unexpected report about the dropped events: %v", records[0].Message)
+	}
+}
@@ -0,0 +1,89 @@
+package kt_logging_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+)
+
+// the messages the memory handler received so far
+func recentMessages(t *testing.T) string {
+	records, err := kt_logging.RecentEvents("handler", kt_logging.RecentEventsFilter{})
+	if err != nil {
+		t.Fatalf("failed to get recent events: %v", err)
+	}
+	return strings.Join(messagesOf(records), ",")
+}
+
+func TestFingersCrossedWritesScopeOnlyOnTrigger(t *testing.T) {
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Type:           "memory",
+		Level:          "debug",
+		FingersCrossed: &kt_logging.FingersCrossedModel{BufferSize: 3},
+	})
+	logger := kt_logging.GetLogger("app")
+
+	succeeding := kt_logging.ContextWithLogBuffer(context.Background())
+	failing := kt_logging.ContextWithLogBuffer(context.Background())
+	logger.Ctx(succeeding).Debug("ok 1")
+	for i := 1; i <= 4; i++ {
+		logger.Ctx(failing).Debug("failing %d", i)
+	}
+	logger.Ctx(succeeding).Info("ok 2")
+	if got := recentMessages(t); got != "" {
+		t.Fatalf("nothing should be written before the trigger but got %v", got)
+	}
+
+	logger.Ctx(failing).Error("failure")
+	// the buffer keeps the last 3 - then the subsequent events of the scope are written right away
+	logger.Ctx(failing).Debug("after failure")
+	if got := recentMessages(t); got != "failing 2,failing 3,failing 4,failure,after failure" {
+		t.Errorf("unexpected events after trigger: %v", got)
+	}
+}
+
+func TestFingersCrossedBuffersAgainAfterTrigger(t *testing.T) {
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Type:           "memory",
+		Level:          "debug",
+		FingersCrossed: &kt_logging.FingersCrossedModel{TriggerLevel: "warning", BufferAfterTrigger: true},
+	})
+	logger := kt_logging.GetLogger("app")
+
+	// without a scope the events share the handler-wide buffer
+	logger.Info("first")
+	logger.Warn("first warning")
+	logger.Info("second")
+	if got := recentMessages(t); got != "first,first warning" {
+		t.Errorf("unexpected events: %v", got)
+	}
+	logger.Error("error")
+	if got := recentMessages(t); got != "first,first warning,second,error" {
+		t.Errorf("unexpected events: %v", got)
+	}
+}
+
+func TestFingersCrossedBuffersEventsWithoutScopeAgainAfterTrigger(t *testing.T) {
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Type:           "memory",
+		Level:          "debug",
+		FingersCrossed: &kt_logging.FingersCrossedModel{},
+	})
+	logger := kt_logging.GetLogger("app")
+
+	logger.Debug("before")
+	logger.Error("error")
+	logger.Debug("after")
+	if got := recentMessages(t); got != "before,error" {
+		t.Errorf("events without scope should be held back again after the trigger but got %v", got)
+	}
+	// while a scope keeps writing after its trigger
+	ctx := kt_logging.ContextWithLogBuffer(context.Background())
+	logger.Ctx(ctx).Error("scoped error")
+	logger.Ctx(ctx).Debug("scoped after")
+	if got := recentMessages(t); got != "before,error,scoped error,scoped after" {
+		t.Errorf("unexpected events: %v", got)
+	}
+}
@@ -0,0 +1,58 @@
+//go:build linux
+
+package kt_logging_test
+
+import (
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+)
+
+// returns the address of a server which does not accept connections - its accept queue is full so connecting to it hangs until
+// the dial timeout
+func startNotAcceptingServer(t *testing.T) string {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatalf("failed to create socket: %v", err)
+	}
+	t.Cleanup(func() { syscall.Close(fd) })
+	if err := syscall.Bind(fd, &syscall.SockaddrInet4{Addr: [4]byte{127, 0, 0, 1}}); err != nil {
+		t.Fatalf("failed to bind: %v", err)
+	}
+	if err := syscall.Listen(fd, 0); err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	sockaddr, err := syscall.Getsockname(fd)
+	if err != nil {
+		t.Fatalf("failed to get address: %v", err)
+	}
+	address := (&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: sockaddr.(*syscall.SockaddrInet4).Port}).String()
+
+	// this one fills up the accept queue
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return address
+}
+
+func TestGelfHandlerDoesNotDialOnEveryEventWhileServerIsUnreachable(t *testing.T) {
+	address := startNotAcceptingServer(t)
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Type:     "gelf",
+		Level:    "debug",
+		Settings: map[string]any{"protocol": "tcp", "address": address, "dialTimeoutMs": 200},
+	})
+	logger := kt_logging.GetLogger("gelf")
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		logger.Error("event %d", i)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("logging waited for the server on every event - took %v", elapsed)
+	}
+}
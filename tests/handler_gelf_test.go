@@ -0,0 +1,126 @@
+package kt_logging_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+)
+
+func TestGelfHandlerChunksCompressedUdpMessages(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Type:     "gelf",
+		Level:    "debug",
+		Settings: map[string]any{"address": listener.LocalAddr().String(), "chunkSize": 300, "host": "myhost"},
+	})
+	// random content so it does not compress well and needs several chunks
+	random := make([]byte, 1000)
+	rand.Read(random)
+	kt_logging.GetLogger("gelf").WithLabels([]kt_logging.Label{
+		kt_logging.StringLabel("payload", hex.EncodeToString(random)), kt_logging.IntLabel("count", 3), kt_logging.StringLabel("id", "x"),
+	}).Error("hello gelf")
+
+	// let's collect the chunks
+	chunks := map[byte][]byte{}
+	var count byte
+	buffer := make([]byte, 4096)
+	for count == 0 || len(chunks) < int(count) {
+		listener.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, _, err := listener.ReadFrom(buffer)
+		if err != nil {
+			t.Fatalf("failed to receive: %v", err)
+		}
+		if n > 300 || buffer[0] != 0x1e || buffer[1] != 0x0f {
+			t.Fatalf("invalid chunk of %d bytes", n)
+		}
+		count = buffer[11]
+		chunks[buffer[10]] = append([]byte{}, buffer[12:n]...)
+	}
+	if count < 2 {
+		t.Errorf("expected multiple chunks but got %d", count)
+	}
+	compressed := []byte{}
+	for seq := byte(0); seq < count; seq++ {
+		compressed = append(compressed, chunks[seq]...)
+	}
+	gzipReader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("message is not gzip compressed: %v", err)
+	}
+	content, _ := io.ReadAll(gzipReader)
+
+	message := map[string]any{}
+	if err := json.Unmarshal(content, &message); err != nil {
+		t.Fatalf("invalid GELF message %s: %v", content, err)
+	}
+	if message["version"] != "1.1" || message["host"] != "myhost" || message["short_message"] != "hello gelf" || message["level"] != float64(3) {
+		t.Errorf("unexpected GELF message %v", message)
+	}
+	if message["_logger"] != "gelf" || message["_count"] != float64(3) || message["_id_"] != "x" || message["_payload"] != hex.EncodeToString(random) {
+		t.Errorf("unexpected additional fields %v", message)
+	}
+}
+
+func TestGelfHandlerOverTcp(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Type:     "gelf",
+		Level:    "debug",
+		Settings: map[string]any{"protocol": "tcp", "address": listener.Addr().String()},
+	})
+	logger := kt_logging.GetLogger("gelf")
+	logger.Info("first")
+	logger.Warn("second")
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("failed to accept: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	reader := bufio.NewReader(conn)
+	for _, expected := range []string{"first", "second"} {
+		frame, err := reader.ReadBytes(0)
+		if err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+		message := map[string]any{}
+		if err := json.Unmarshal(frame[:len(frame)-1], &message); err != nil {
+			t.Fatalf("invalid GELF message %s: %v", frame, err)
+		}
+		if message["short_message"] != expected {
+			t.Errorf("expected %v but got %v", expected, message)
+		}
+	}
+}
+
+func TestGelfHandlerDoesNotBlockOnServerNotReading(t *testing.T) {
+	listener := startNotReadingServer(t)
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Type:     "gelf",
+		Level:    "debug",
+		Settings: map[string]any{"protocol": "tcp", "address": listener.Addr().String(), "writeTimeoutMs": 50},
+	})
+	if !logsWithoutBlocking(kt_logging.GetLogger("gelf")) {
+		t.Fatalf("logging was blocked by the gelf server not reading")
+	}
+}
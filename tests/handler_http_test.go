@@ -0,0 +1,137 @@
+package kt_logging_test
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+)
+
+// collects the NDJSON documents POSTed to it - responding with the given status codes in order (then 200)
+type ndjsonReceiver struct {
+	lock      sync.Mutex
+	statuses  []int
+	requests  int
+	documents []map[string]any
+	headers   []http.Header
+}
+
+func (r *ndjsonReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.requests++
+	r.headers = append(r.headers, req.Header.Clone())
+	if len(r.statuses) > 0 {
+		status := r.statuses[0]
+		r.statuses = r.statuses[1:]
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+	}
+
+	body := req.Body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gzipReader, err := gzip.NewReader(req.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body = gzipReader
+	}
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		document := map[string]any{}
+		if err := json.Unmarshal(scanner.Bytes(), &document); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.documents = append(r.documents, document)
+	}
+}
+
+func TestHttpHandlerShipsBatches(t *testing.T) {
+	receiver := &ndjsonReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Type:  "http",
+		Level: "debug",
+		Settings: map[string]any{
+			"batchSize":       2,
+			"flushIntervalMs": 60000,
+			"url":             server.URL,
+			"headers":         map[string]string{"Authorization": "Bearer secret"},
+			"gzip":            true,
+		},
+	})
+	logger := kt_logging.GetLogger("http")
+	logger.Info("event %d", 1)
+	logger.WithLabel(kt_logging.IntLabel("count", 2)).Warn("event %d", 2)
+	logger.Info("event %d", 3)
+	if err := kt_logging.Sync(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	// the first batch is full after 2 events, the third one is sent by Sync()
+	if receiver.requests != 2 {
+		t.Errorf("expected 2 requests but got %d", receiver.requests)
+	}
+	if len(receiver.documents) != 3 {
+		t.Fatalf("expected 3 log events but got %v", receiver.documents)
+	}
+	if receiver.documents[1]["message"] != "event 2" || receiver.documents[1]["level"] != "warn" || receiver.documents[1]["count"] != float64(2) {
+		t.Errorf("unexpected log event %v", receiver.documents[1])
+	}
+	if receiver.documents[2]["logger"] != "http" {
+		t.Errorf("unexpected log event %v", receiver.documents[2])
+	}
+	for _, header := range receiver.headers {
+		if header.Get("Authorization") != "Bearer secret" || header.Get("Content-Type") != "application/x-ndjson" {
+			t.Errorf("unexpected request headers %v", header)
+		}
+	}
+}
+
+func TestHttpHandlerRetriesServerErrorsOnly(t *testing.T) {
+	// the first batch fails with 503 once then gets through - the second batch is rejected with 400 which is not retried
+	receiver := &ndjsonReceiver{statuses: []int{http.StatusServiceUnavailable, http.StatusOK, http.StatusBadRequest}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Type:     "http",
+		Level:    "debug",
+		Settings: map[string]any{"batchSize": 1, "flushIntervalMs": 60000, "maxRetries": 2, "url": server.URL},
+	})
+	logger := kt_logging.GetLogger("http")
+	logger.Info("retried")
+	logger.Info("rejected")
+	logger.Info("accepted")
+	// Sync() fails fast while the endpoint is failing - so we keep trying until everything is processed
+	deadline := time.Now().Add(5 * time.Second)
+	for kt_logging.Sync() != nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("batches were not processed in time")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	if receiver.requests != 4 {
+		t.Errorf("expected 4 requests but got %d", receiver.requests)
+	}
+	if len(receiver.documents) != 2 || receiver.documents[0]["message"] != "retried" || receiver.documents[1]["message"] != "accepted" {
+		t.Errorf("unexpected log events %v", receiver.documents)
+	}
+}
@@ -0,0 +1,112 @@
+//go:build linux
+
+package kt_logging_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+)
+
+// parses the native journal protocol
+func parseJournalEntry(t *testing.T, entry []byte) map[string]string {
+	fields := map[string]string{}
+	for len(entry) > 0 {
+		lineEnd := bytes.IndexByte(entry, '\n')
+		if lineEnd < 0 {
+			t.Fatalf("unterminated journal field %q", entry)
+		}
+		line := string(entry[:lineEnd])
+		entry = entry[lineEnd+1:]
+		if name, value, found := strings.Cut(line, "="); found {
+			fields[name] = value
+			continue
+		}
+		// binary safe field: length then value then newline
+		length := binary.LittleEndian.Uint64(entry[:8])
+		fields[line] = string(entry[8 : 8+length])
+		entry = entry[8+length+1:]
+	}
+	return fields
+}
+
+// receives one journal entry - either in the datagram or in the passed memfd
+func receiveJournalEntry(t *testing.T, listener *net.UnixConn) (map[string]string, bool) {
+	buffer := make([]byte, 65536)
+	oob := make([]byte, 1024)
+	listener.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, oobn, _, _, err := listener.ReadMsgUnix(buffer, oob)
+	if err != nil {
+		t.Fatalf("failed to receive: %v", err)
+	}
+	if oobn == 0 {
+		return parseJournalEntry(t, buffer[:n]), false
+	}
+	messages, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("failed to parse control message: %v", err)
+	}
+	fds, err := syscall.ParseUnixRights(&messages[0])
+	if err != nil || len(fds) != 1 {
+		t.Fatalf("expected a file descriptor: %v", err)
+	}
+	file := os.NewFile(uintptr(fds[0]), "memfd")
+	defer file.Close()
+	// the offset is shared with the sender which left it at the end (journald itself maps the memfd)
+	file.Seek(0, io.SeekStart)
+	content, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatalf("failed to read memfd: %v", err)
+	}
+	return parseJournalEntry(t, content), true
+}
+
+func TestJournaldHandlerWritesStructuredFields(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "journal.socket")
+	listener, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Type:     "journald",
+		Level:    "debug",
+		Settings: map[string]any{"socketPath": socketPath, "identifier": "myapp"},
+	})
+	logger := kt_logging.GetLogger("journal")
+	logger.WithLabels([]kt_logging.Label{
+		kt_logging.StringLabel("user.id", "john"), kt_logging.StringLabel("_trusted", "no"), kt_logging.StringLabel("multi", "line1\nline2"),
+	}).Warn("hello journal")
+
+	fields, inMemfd := receiveJournalEntry(t, listener)
+	if inMemfd {
+		t.Errorf("small entry should be sent in the datagram")
+	}
+	if fields["PRIORITY"] != "4" || fields["MESSAGE"] != "hello journal" || fields["SYSLOG_IDENTIFIER"] != "myapp" || fields["LOGGER"] != "journal" {
+		t.Errorf("unexpected journal entry %v", fields)
+	}
+	if fields["USER_ID"] != "john" || fields["TRUSTED"] != "no" || fields["MULTI"] != "line1\nline2" {
+		t.Errorf("unexpected label fields %v", fields)
+	}
+
+	// too big for a datagram - goes via memfd
+	big := strings.Repeat("x", 1024*1024)
+	logger.WithLabel(kt_logging.StringLabel("big", big)).Error("big entry")
+	fields, inMemfd = receiveJournalEntry(t, listener)
+	if !inMemfd {
+		t.Errorf("big entry should be sent in memfd")
+	}
+	if fields["MESSAGE"] != "big entry" || fields["BIG"] != big {
+		t.Errorf("unexpected big journal entry with message %q", fields["MESSAGE"])
+	}
+}
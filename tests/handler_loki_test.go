@@ -0,0 +1,114 @@
+package kt_logging_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+)
+
+type lokiPushRequest struct {
+	Streams []struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	} `json:"streams"`
+}
+
+// collects the log lines pushed to it - by stream
+type lokiReceiver struct {
+	lock     sync.Mutex
+	tenantID string
+	// stream labels as JSON -> log lines
+	lines map[string][]map[string]any
+}
+
+func (r *lokiReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.tenantID = req.Header.Get("X-Scope-OrgID")
+	push := lokiPushRequest{}
+	if err := json.NewDecoder(req.Body).Decode(&push); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	for _, stream := range push.Streams {
+		streamLabels, _ := json.Marshal(stream.Stream)
+		for _, value := range stream.Values {
+			line := map[string]any{}
+			if err := json.Unmarshal([]byte(value[1]), &line); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			r.lines[string(streamLabels)] = append(r.lines[string(streamLabels)], line)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestLokiHandlerUsesGlobalLabelsAndLevelAsStreamLabels(t *testing.T) {
+	receiver := &lokiReceiver{lines: map[string][]map[string]any{}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	kt_logging.SetGlobalLabels([]kt_logging.Label{kt_logging.StringLabel("appName", "my-app")})
+	defer kt_logging.SetGlobalLabels(nil)
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Type:     "loki",
+		Level:    "debug",
+		Settings: map[string]any{"url": server.URL, "tenantId": "team-a"},
+	})
+	logger := kt_logging.GetLogger("loki")
+	logger.WithLabel(kt_logging.StringLabel("user", "john")).Info("hello")
+	logger.Error("failed")
+	if err := kt_logging.Sync(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	if receiver.tenantID != "team-a" {
+		t.Errorf("expected tenant header but got %q", receiver.tenantID)
+	}
+	infoLines := receiver.lines[`{"appName":"my-app","level":"info"}`]
+	if len(infoLines) != 1 || infoLines[0]["message"] != "hello" || infoLines[0]["user"] != "john" || infoLines[0]["logger"] != "loki" {
+		t.Errorf("unexpected info stream %v", receiver.lines)
+	}
+	if _, contains := infoLines[0]["appName"]; contains {
+		t.Errorf("stream labels should not be repeated in the log line: %v", infoLines[0])
+	}
+	errorLines := receiver.lines[`{"appName":"my-app","level":"error"}`]
+	if len(errorLines) != 1 || errorLines[0]["message"] != "failed" {
+		t.Errorf("unexpected error stream %v", receiver.lines)
+	}
+}
+
+func TestLokiHandlerCapsStreams(t *testing.T) {
+	receiver := &lokiReceiver{lines: map[string][]map[string]any{}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Type:     "loki",
+		Level:    "debug",
+		Settings: map[string]any{"url": server.URL, "streamLabels": []string{"logger", "tenant"}, "maxStreams": 2},
+	})
+	for _, tenant := range []string{"a", "b", "c"} {
+		kt_logging.GetLogger("loki").WithLabel(kt_logging.StringLabel("tenant", tenant)).Info("hello %s", tenant)
+	}
+	if err := kt_logging.Sync(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+	if len(receiver.lines[`{"logger":"loki","tenant":"a"}`]) != 1 || len(receiver.lines[`{"logger":"loki","tenant":"b"}`]) != 1 {
+		t.Errorf("unexpected streams %v", receiver.lines)
+	}
+	overflowLines := receiver.lines[`{"overflow":"true"}`]
+	if len(overflowLines) != 1 || overflowLines[0]["tenant"] != "c" || overflowLines[0]["logger"] != "loki" || overflowLines[0]["level"] != "info" {
+		t.Errorf("unexpected overflow stream %v", receiver.lines)
+	}
+}
@@ -0,0 +1,136 @@
+package kt_logging_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+)
+
+func messagesOf(records []kt_logging.LogRecord) []string {
+	messages := []string{}
+	for _, record := range records {
+		messages = append(messages, record.Message)
+	}
+	return messages
+}
+
+func TestMemoryHandlerKeepsRecentEvents(t *testing.T) {
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Type:     "memory",
+		Level:    "debug",
+		Settings: map[string]any{"maxEvents": 5},
+	})
+	for i := 0; i < 8; i++ {
+		kt_logging.GetLogger("app.db").Info("event %d", i)
+	}
+	kt_logging.GetLogger("app").WithLabel(kt_logging.StringLabel("user", "john")).Error("failure")
+
+	records, err := kt_logging.RecentEvents("handler", kt_logging.RecentEventsFilter{})
+	if err != nil {
+		t.Fatalf("failed to get recent events: %v", err)
+	}
+	if got := strings.Join(messagesOf(records), ","); got != "event 4,event 5,event 6,event 7,failure" {
+		t.Errorf("unexpected recent events: %v", got)
+	}
+	last := records[len(records)-1]
+	if last.LoggerName != "app" || last.Level != kt_logging.ErrorLevel || len(last.Labels) != 1 || last.Labels[0].GetKey() != "user" || last.Time.IsZero() {
+		t.Errorf("unexpected record %+v", last)
+	}
+
+	for _, testCase := range []struct {
+		filter   kt_logging.RecentEventsFilter
+		expected string
+	}{
+		{kt_logging.RecentEventsFilter{Level: kt_logging.WarningLevel}, "failure"},
+		{kt_logging.RecentEventsFilter{LoggerName: "app.db"}, "event 4,event 5,event 6,event 7"},
+		{kt_logging.RecentEventsFilter{LoggerName: "app.d"}, ""},
+		{kt_logging.RecentEventsFilter{Contains: "event", Limit: 2}, "event 6,event 7"},
+	} {
+		records, _ := kt_logging.RecentEvents("handler", testCase.filter)
+		if got := strings.Join(messagesOf(records), ","); got != testCase.expected {
+			t.Errorf("filter %+v returned %v instead of %v", testCase.filter, got, testCase.expected)
+		}
+	}
+
+	if _, err := kt_logging.RecentEvents("missing", kt_logging.RecentEventsFilter{}); err == nil {
+		t.Errorf("missing handler should fail")
+	}
+}
+
+func TestMemoryHandlerLimitsBytes(t *testing.T) {
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Type:     "memory",
+		Level:    "debug",
+		Settings: map[string]any{"maxBytes": 1000},
+	})
+	payload := strings.Repeat("x", 300)
+	for i := 0; i < 10; i++ {
+		kt_logging.GetLogger("app").Info("%d %v", i, payload)
+	}
+	records, _ := kt_logging.RecentEvents("handler", kt_logging.RecentEventsFilter{})
+	if len(records) < 2 || len(records) > 3 || !strings.HasPrefix(records[len(records)-1].Message, "9 ") {
+		t.Errorf("expected the last 2-3 events but got %d", len(records))
+	}
+}
+
+func TestMemoryHandlerSettingsFromYaml(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "log-config.yaml")
+	writeFile(t, cfgPath, `
+loggers:
+  root:
+    level: debug
+    handlers:
+      - handler
+handlers:
+  handler:
+    type: memory
+    level: debug
+    settings:
+      maxEvents: 2
+`)
+	if err := kt_logging.InitFromConfig(cfgPath); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	logger := kt_logging.GetLogger("app")
+	for i := 0; i < 3; i++ {
+		logger.Info("event %d", i)
+	}
+	if got := recentMessages(t); got != "event 1,event 2" {
+		t.Errorf("expected the last 2 events but got %v", got)
+	}
+}
+
+func TestRecentEventsHandlerDumpsNdjson(t *testing.T) {
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{Type: "memory", Level: "debug"})
+	kt_logging.GetLogger("app").Info("first")
+	kt_logging.GetLogger("app").WithLabel(kt_logging.IntLabel("count", 3)).Warn("second")
+
+	recorder := httptest.NewRecorder()
+	kt_logging.RecentEventsHandler("handler").ServeHTTP(recorder, httptest.NewRequest("GET", "/logs?level=warning&since=1m", nil))
+	if recorder.Code != 200 || recorder.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("unexpected response %d %v", recorder.Code, recorder.Header())
+	}
+	lines := []map[string]any{}
+	scanner := bufio.NewScanner(recorder.Body)
+	for scanner.Scan() {
+		line := map[string]any{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("invalid line %s: %v", scanner.Bytes(), err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 1 || lines[0]["message"] != "second" || lines[0]["level"] != "warn" || lines[0]["count"] != float64(3) || lines[0]["logger"] != "app" {
+		t.Errorf("unexpected dump %v", lines)
+	}
+
+	recorder = httptest.NewRecorder()
+	kt_logging.RecentEventsHandler("handler").ServeHTTP(recorder, httptest.NewRequest("GET", "/logs?limit=x", nil))
+	if recorder.Code != 400 {
+		t.Errorf("invalid limit should be rejected but got %d", recorder.Code)
+	}
+}
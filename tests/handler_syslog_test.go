@@ -0,0 +1,182 @@
+package kt_logging_test
+
+import (
+	"bufio"
+	"net"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+)
+
+func initWithSingleHandler(t *testing.T, handler kt_logging.HandlerConfigModel) {
+	cfgPath := filepath.Join(t.TempDir(), "log-config.json")
+	writeJsonConfig(t, cfgPath, kt_logging.ConfigModel{
+		Loggers:  map[string]kt_logging.LoggerConfigModel{"root": {Level: "debug", HandlerNames: []string{"handler"}}},
+		Handlers: map[string]kt_logging.HandlerConfigModel{"handler": handler},
+	})
+	if err := kt_logging.InitFromConfig(cfgPath); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+}
+
+func TestSyslogHandlerRFC5424OverUDP(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Type:  "syslog",
+		Level: "debug",
+		Settings: map[string]any{
+			"network": "udp", "address": listener.LocalAddr().String(), "facility": "local0", "appName": "myapp", "hostname": "myhost",
+		},
+	})
+	kt_logging.GetLogger("syslog").WithLabel(kt_logging.StringLabel("quote", `say "hi"]`)).Warn("hello syslog")
+
+	buffer := make([]byte, 4096)
+	listener.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := listener.ReadFrom(buffer)
+	if err != nil {
+		t.Fatalf("failed to receive: %v", err)
+	}
+	// local0 (16) * 8 + warning (4) = 132
+	expected := regexp.MustCompile(`^<132>1 \S+ myhost myapp \d+ - \[labels@32473 logger="syslog" quote="say \\"hi\\"\\]"\] hello syslog$`)
+	if !expected.Match(buffer[:n]) {
+		t.Errorf("unexpected syslog message: %s", buffer[:n])
+	}
+}
+
+func TestSyslogHandlerRFC3164OverTCPReconnects(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Type:  "syslog",
+		Level: "debug",
+		Settings: map[string]any{
+			"network": "tcp", "address": listener.Addr().String(), "format": "rfc3164", "framing": "newline", "appName": "myapp", "hostname": "myhost",
+		},
+	})
+	logger := kt_logging.GetLogger("syslog")
+
+	// the first connection receives one message then the server drops it
+	logger.Error("first")
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("failed to accept: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	// user (1) * 8 + error (3) = 11
+	if !strings.HasPrefix(line, "<11>") || !strings.Contains(line, " myhost myapp[") || !strings.HasSuffix(line, `]: first logger="syslog"`+"\n") {
+		t.Errorf("unexpected syslog message: %q", line)
+	}
+	conn.Close()
+
+	// writes into a connection closed by the peer might succeed for a while - so we keep logging until the reconnect happens
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		logger.Error("second")
+		select {
+		case conn := <-accepted:
+			conn.Close()
+			return
+		case <-time.After(20 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("handler did not reconnect")
+		}
+	}
+}
+
+// accepts connections but never reads from them
+func startNotReadingServer(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		conns := []net.Conn{}
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+	return listener
+}
+
+// logs big messages - returns FALSE if logging got stuck
+func logsWithoutBlocking(logger *kt_logging.Logger) bool {
+	message := strings.Repeat("x", 1024*1024)
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 30; i++ {
+			logger.Error("%s", message)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(10 * time.Second):
+		return false
+	}
+}
+
+func TestSyslogHandlerDoesNotBlockOnServerNotReading(t *testing.T) {
+	listener := startNotReadingServer(t)
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Type:     "syslog",
+		Level:    "debug",
+		Settings: map[string]any{"network": "tcp", "address": listener.Addr().String(), "writeTimeoutMs": 50},
+	})
+	if !logsWithoutBlocking(kt_logging.GetLogger("syslog")) {
+		t.Fatalf("logging was blocked by the syslog server not reading")
+	}
+}
+
+func TestSyslogHandlerDoesNotDialOnEveryEventWhileServerIsUnreachable(t *testing.T) {
+	// the TLS handshake never completes - so every dial attempt takes the dial timeout
+	listener := startNotReadingServer(t)
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Type:     "syslog",
+		Level:    "debug",
+		Settings: map[string]any{"network": "tls", "address": listener.Addr().String(), "dialTimeoutMs": 200},
+	})
+	logger := kt_logging.GetLogger("syslog")
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		logger.Error("event %d", i)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("logging waited for the server on every event - took %v", elapsed)
+	}
+}
@@ -0,0 +1,177 @@
+package kt_logging_test
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+)
+
+// a custom handler collecting the records in memory
+type captureHandler struct {
+	lock     sync.Mutex
+	prefix   string
+	records  []kt_logging.LogRecord
+	syncs    int
+	isClosed bool
+}
+
+func (h *captureHandler) Handle(record kt_logging.LogRecord) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	record.Message = h.prefix + record.Message
+	h.records = append(h.records, record)
+	return nil
+}
+
+func (h *captureHandler) Sync() error {
+	h.syncs++
+	return nil
+}
+
+func (h *captureHandler) Close() error {
+	h.isClosed = true
+	return nil
+}
+
+func TestCustomHandlerType(t *testing.T) {
+	var created *captureHandler
+	kt_logging.RegisterHandlerType("capture", func(handlerName string, cfg kt_logging.HandlerConfigModel) (kt_logging.Handler, error) {
+		settings := struct {
+			Prefix string `json:"prefix"`
+		}{}
+		if err := cfg.DecodeSettings(&settings); err != nil {
+			return nil, err
+		}
+		created = &captureHandler{prefix: settings.Prefix}
+		return created, nil
+	})
+
+	cfgPath := filepath.Join(t.TempDir(), "log-config.yaml")
+	writeFile(t, cfgPath, `
+loggers:
+  root:
+    level: debug
+    handlers:
+      - custom
+handlers:
+  custom:
+    type: capture
+    level: info
+    settings:
+      prefix: "custom: "
+`)
+	if err := kt_logging.InitFromConfig(cfgPath); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	handler := created
+
+	kt_logging.GetLogger("app").WithLabel(kt_logging.StringLabel("key", "value")).Info("hello %v", "world")
+	kt_logging.GetLogger("app").Debug("filtered out by the handler level")
+	if err := kt_logging.Sync(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	if len(handler.records) != 1 {
+		t.Fatalf("expected 1 record, got %v", handler.records)
+	}
+	record := handler.records[0]
+	if record.Message != "custom: hello world" || record.LoggerName != "app" || record.Level != kt_logging.InfoLevel || record.Time.IsZero() {
+		t.Errorf("unexpected record: %+v", record)
+	}
+	if len(record.Labels) != 1 || record.Labels[0].GetKey() != "key" {
+		t.Errorf("unexpected labels: %+v", record.Labels)
+	}
+	if handler.syncs != 1 {
+		t.Errorf("expected handler to be synced once, got %v", handler.syncs)
+	}
+
+	// the old handler must be closed once it is replaced
+	initWithJsonFileOutput(t)
+	if !handler.isClosed {
+		t.Errorf("replaced handler should be closed")
+	}
+}
+
+func TestUnknownHandlerType(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "log-config.yaml")
+	writeFile(t, cfgPath, `
+loggers:
+  root:
+    level: debug
+    handlers:
+      - custom
+handlers:
+  custom:
+    type: doesnotexist
+    level: info
+`)
+	if err := kt_logging.InitFromConfig(cfgPath); err == nil {
+		t.Fatalf("expected init to fail on unknown handler type")
+	}
+}
+
+func TestHandlerWithoutLevelIsOnInfoLevel(t *testing.T) {
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{Type: "memory"})
+	logger := kt_logging.GetLogger("app")
+	logger.Debug("debug")
+	logger.Info("info")
+	if got := recentMessages(t); got != "info" {
+		t.Errorf("expected only the info event but got %v", got)
+	}
+}
+
+func TestZapHandlerWithoutLevelIsOnInfoLevel(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "log.jsonl")
+	cfgPath := filepath.Join(dir, "log-config.yaml")
+	writeFile(t, cfgPath, `
+loggers:
+  root:
+    level: debug
+    handlers:
+      - file
+handlers:
+  file:
+    encoding: json
+    outputPaths:
+      - `+logPath+`
+`)
+	if err := kt_logging.InitFromConfig(cfgPath); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	logger := kt_logging.GetLogger("app")
+	logger.Debug("debug")
+	logger.Info("info")
+	kt_logging.Sync()
+
+	events := readJsonLogEvents(t, logPath)
+	if len(events) != 1 || events[0]["message"] != "info" {
+		t.Errorf("expected only the info event but got %v", events)
+	}
+}
+
+func TestHandlerOnPanicLevel(t *testing.T) {
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{Type: "memory", Level: "dpanic"})
+	logger := kt_logging.GetLogger("app")
+	logger.Error("error")
+	func() {
+		defer func() { recover() }()
+		logger.Panic("panic")
+	}()
+	if got := recentMessages(t); got != "panic" {
+		t.Errorf("expected only the panic event but got %v", got)
+	}
+}
+
+func TestGetHandlersReturnsZapLoggers(t *testing.T) {
+	initWithJsonFileOutput(t)
+	logger := kt_logging.GetLogger("root")
+	if zapLogger, found := logger.GetHandlers()["file_json"]; !found || zapLogger == nil {
+		t.Errorf("expected the zap Logger of the handler but got %v", logger.GetHandlers())
+	}
+	if _, isZap := logger.GetOutputHandlers()["file_json"].(*kt_logging.ZapHandler); !isZap {
+		t.Errorf("expected the zap Handler but got %v", logger.GetOutputHandlers())
+	}
+}
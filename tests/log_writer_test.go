@@ -0,0 +1,44 @@
+package kt_logging_test
+
+import (
+	"fmt"
+	"log"
+	"testing"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+)
+
+func TestWriterEmitsOneEventPerLine(t *testing.T) {
+	logPath := initWithJsonFileOutput(t)
+
+	writer := kt_logging.GetLogger("writer").Writer(kt_logging.WarningLevel)
+	fmt.Fprint(writer, "first line\nsecond ")
+	fmt.Fprint(writer, "line\r\n\nincomplete")
+
+	events := readJsonLogEvents(t, logPath)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %v", events)
+	}
+	if events[0]["message"] != "first line" || events[1]["message"] != "second line" {
+		t.Errorf("unexpected messages: %v", events)
+	}
+	if events[0]["level"] != "warn" || events[0]["logger"] != "writer" {
+		t.Errorf("unexpected event: %v", events[0])
+	}
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	logPath := initWithJsonFileOutput(t)
+
+	restore := kt_logging.RedirectStdLog("stdlog", kt_logging.InfoLevel)
+	log.Printf("hello from %v with 100%%", "log")
+	restore()
+
+	events := readJsonLogEvents(t, logPath)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %v", events)
+	}
+	if events[0]["message"] != "hello from log with 100%" || events[0]["logger"] != "stdlog" || events[0]["level"] != "info" {
+		t.Errorf("unexpected event: %v", events[0])
+	}
+}
@@ -0,0 +1,95 @@
+package kt_logging_test
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+)
+
+func TestSetLevelPropagatesToInheritingChildren(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "log-config.yaml")
+	writeReloadTestConfig(t, cfgPath, "warning")
+	if err := kt_logging.InitFromConfig(cfgPath); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+
+	child := kt_logging.GetLogger("controller.something")
+	grandChild := kt_logging.GetLogger("controller.something.deeper")
+	explicitChild := kt_logging.GetLogger("controller.explicit")
+	explicitChild.SetLevel(kt_logging.ErrorLevel)
+
+	kt_logging.SetLevel("controller", kt_logging.DebugLevel)
+
+	if child.GetLevel() != kt_logging.DebugLevel || grandChild.GetLevel() != kt_logging.DebugLevel {
+		t.Fatalf("inheriting children should follow the new level, got %v and %v", child.GetLevel(), grandChild.GetLevel())
+	}
+	if explicitChild.GetLevel() != kt_logging.ErrorLevel {
+		t.Fatalf("explicitly set child should keep its own level, got %v", explicitChild.GetLevel())
+	}
+	if !child.IsDebugEnabled() {
+		t.Fatalf("child should have debug enabled")
+	}
+
+	// a child of an explicitly set logger follows that one and not the "controller"
+	explicitGrandChild := kt_logging.GetLogger("controller.explicit.deeper")
+	kt_logging.SetLevel("controller", kt_logging.InfoLevel)
+	if explicitGrandChild.GetLevel() != kt_logging.ErrorLevel {
+		t.Fatalf("expected grand child to follow its explicitly set parent, got %v", explicitGrandChild.GetLevel())
+	}
+}
+
+func TestSetLevelIsGoroutineSafe(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "log-config.yaml")
+	writeReloadTestConfig(t, cfgPath, "warning")
+	if err := kt_logging.InitFromConfig(cfgPath); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			kt_logging.SetLevel("controller", kt_logging.DebugLevel)
+		}()
+		go func() {
+			defer wg.Done()
+			kt_logging.GetLogger("controller.something").Debug("concurrent log")
+		}()
+	}
+	wg.Wait()
+}
+
+func TestTraceAndPanicLevels(t *testing.T) {
+	logPath := initWithJsonFileOutput(t)
+	logger := kt_logging.GetLogger("levels")
+
+	logger.Trace("not visible on debug level")
+	if logger.IsTraceEnabled() {
+		t.Fatalf("trace should not be enabled on debug level")
+	}
+	logger.SetLevel(kt_logging.TraceLevel)
+	logger.Trace("visible on trace level")
+
+	func() {
+		defer func() {
+			if recovered := recover(); recovered != "something %went wrong" {
+				t.Errorf("expected panic with the message, got %v", recovered)
+			}
+		}()
+		logger.WithLabel(kt_logging.IntLabel("code", 5)).Panic("something %v", "%went wrong")
+	}()
+
+	events := readJsonLogEvents(t, logPath)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %v", events)
+	}
+	if events[0]["level"] != "trace" || events[0]["message"] != "visible on trace level" {
+		t.Errorf("unexpected trace event: %v", events[0])
+	}
+	if events[1]["level"] != "panic" || events[1]["code"] != float64(5) {
+		t.Errorf("unexpected panic event: %v", events[1])
+	}
+}
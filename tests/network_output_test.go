@@ -0,0 +1,88 @@
+package kt_logging_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+)
+
+// reads octet-counted frames from the reader
+func readOctetCountedFrames(t *testing.T, reader *bufio.Reader, count int) []string {
+	frames := []string{}
+	for len(frames) < count {
+		lengthStr, err := reader.ReadString(' ')
+		if err != nil {
+			t.Fatalf("failed to read frame length: %v", err)
+		}
+		length, err := strconv.Atoi(strings.TrimSuffix(lengthStr, " "))
+		if err != nil {
+			t.Fatalf("invalid frame length %q", lengthStr)
+		}
+		frame := make([]byte, length)
+		if _, err := io.ReadFull(reader, frame); err != nil {
+			t.Fatalf("failed to read frame: %v", err)
+		}
+		frames = append(frames, string(frame))
+	}
+	return frames
+}
+
+func TestNetworkOutputBuffersWhileDisconnected(t *testing.T) {
+	// let's find a free port - nobody listens there for now
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Level:    "debug",
+		Encoding: "json",
+		Network: &kt_logging.NetworkModel{
+			Protocol: "tcp", Address: address, Framing: "octet-counting", BufferSize: 3, Overflow: "dropNewest", MaxReconnectDelaySec: 1,
+		},
+	})
+	logger := kt_logging.GetLogger("network")
+	for i := 0; i < 5; i++ {
+		logger.Info("event %d", i)
+	}
+	if err := kt_logging.Sync(); err == nil {
+		t.Errorf("sync should fail while the endpoint is not reachable")
+	}
+
+	listener, err = net.Listen("tcp", address)
+	if err != nil {
+		t.Fatalf("failed to listen again: %v", err)
+	}
+	defer listener.Close()
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("failed to accept: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	frames := readOctetCountedFrames(t, bufio.NewReader(conn), 3)
+
+	// the first event might have been taken by the sender already so the buffer could keep one more
+	for i, frame := range frames {
+		event := map[string]any{}
+		if err := json.Unmarshal([]byte(frame), &event); err != nil {
+			t.Fatalf("invalid JSON frame %q: %v", frame, err)
+		}
+		if event["message"] != fmt.Sprintf("event %d", i) || event["logger"] != "network" {
+			t.Errorf("unexpected event #%d: %v", i, event)
+		}
+	}
+	if err := kt_logging.Sync(); err != nil {
+		t.Errorf("sync should succeed once connected: %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package kt_logging_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestOtelTraceCorrelationLabels(t *testing.T) {
+	logPath := initWithJsonFileOutput(t)
+	kt_otel.Register(kt_otel.KeyNames{TraceID: "traceId", SpanID: "spanId", TraceFlags: "traceFlags"})
+	defer kt_otel.Unregister()
+
+	recorder := tracetest.NewSpanRecorder()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ctx, span := tracerProvider.Tracer("test").Start(context.Background(), "operation")
+
+	kt_logging.GetLogger("traced").Ctx(ctx).Info("inside span")
+	kt_logging.GetLogger("traced").Ctx(context.Background()).Info("outside span")
+	span.End()
+
+	events := readJsonLogEvents(t, logPath)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %v", events)
+	}
+	spanContext := span.SpanContext()
+	if events[0]["traceId"] != spanContext.TraceID().String() || events[0]["spanId"] != spanContext.SpanID().String() || events[0]["traceFlags"] != "01" {
+		t.Errorf("unexpected trace labels on event: %v", events[0])
+	}
+	if _, contains := events[1]["traceId"]; contains {
+		t.Errorf("event without span should not carry trace labels: %v", events[1])
+	}
+}
@@ -0,0 +1,225 @@
+package kt_logging_test
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_otel"
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_otlp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// collects the exported logs - it is an OTLP HTTP and gRPC receiver at the same time
+type otlpReceiver struct {
+	collogspb.UnimplementedLogsServiceServer
+	lock     sync.Mutex
+	requests []*collogspb.ExportLogsServiceRequest
+}
+
+func (r *otlpReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	request := &collogspb.ExportLogsServiceRequest{}
+	if req.URL.Path != "/v1/logs" || proto.Unmarshal(body, request) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.lock.Lock()
+	r.requests = append(r.requests, request)
+	r.lock.Unlock()
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	response, _ := proto.Marshal(&collogspb.ExportLogsServiceResponse{})
+	w.Write(response)
+}
+
+func (r *otlpReceiver) Export(_ context.Context, request *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	r.lock.Lock()
+	r.requests = append(r.requests, request)
+	r.lock.Unlock()
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+// returns the exported log records along with the resource attributes
+func (r *otlpReceiver) logRecords() ([]*logspb.LogRecord, []*logspb.ScopeLogs, []*commonpb.KeyValue) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	records := []*logspb.LogRecord{}
+	scopes := []*logspb.ScopeLogs{}
+	resourceAttributes := []*commonpb.KeyValue{}
+	for _, request := range r.requests {
+		for _, resourceLogs := range request.ResourceLogs {
+			resourceAttributes = resourceLogs.Resource.Attributes
+			for _, scopeLogs := range resourceLogs.ScopeLogs {
+				scopes = append(scopes, scopeLogs)
+				records = append(records, scopeLogs.LogRecords...)
+			}
+		}
+	}
+	return records, scopes, resourceAttributes
+}
+
+func findAttribute(attributes []*commonpb.KeyValue, key string) *commonpb.AnyValue {
+	for _, attribute := range attributes {
+		if attribute.Key == key {
+			return attribute.Value
+		}
+	}
+	return nil
+}
+
+func initWithOtlpHandler(t *testing.T, settings map[string]any) {
+	kt_otlp.RegisterOtlpHandlerType()
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{Type: "otlp", Level: "debug", Settings: settings})
+}
+
+func TestOtlpHandlerOverHttp(t *testing.T) {
+	receiver := &otlpReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	kt_logging.SetGlobalLabels([]kt_logging.Label{kt_logging.StringLabel("service.name", "my-service")})
+	defer kt_logging.SetGlobalLabels(nil)
+	kt_otel.Register(kt_otel.DefaultKeyNames())
+	defer kt_otel.Unregister()
+	initWithOtlpHandler(t, map[string]any{"endpoint": server.URL})
+
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "operation")
+	kt_logging.GetLogger("otlp").Ctx(ctx).WithLabels([]kt_logging.Label{
+		kt_logging.BoolLabel("flag", true), kt_logging.IntLabel("count", 42), kt_logging.FloatLabel("ratio", 0.5), kt_logging.StringLabel("user", "john"),
+	}).Warn("hello %s", "otlp")
+	span.End()
+	if err := kt_logging.Sync(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	records, scopes, resourceAttributes := receiver.logRecords()
+	if len(records) != 1 {
+		t.Fatalf("expected 1 log record but got %v", records)
+	}
+	record := records[0]
+	if record.Body.GetStringValue() != "hello otlp" || record.SeverityNumber != logspb.SeverityNumber_SEVERITY_NUMBER_WARN || record.SeverityText != "WARNING" {
+		t.Errorf("unexpected log record %v", record)
+	}
+	if scopes[0].Scope.Name != "otlp" {
+		t.Errorf("expected the logger name as scope but got %v", scopes[0].Scope)
+	}
+	if findAttribute(record.Attributes, "flag").GetBoolValue() != true || findAttribute(record.Attributes, "count").GetIntValue() != 42 ||
+		findAttribute(record.Attributes, "ratio").GetDoubleValue() != 0.5 || findAttribute(record.Attributes, "user").GetStringValue() != "john" {
+		t.Errorf("unexpected attributes %v", record.Attributes)
+	}
+	if findAttribute(record.Attributes, "trace_id") != nil {
+		t.Errorf("trace correlation labels should not be attributes %v", record.Attributes)
+	}
+	if hex.EncodeToString(record.TraceId) != span.SpanContext().TraceID().String() || hex.EncodeToString(record.SpanId) != span.SpanContext().SpanID().String() {
+		t.Errorf("unexpected trace context %x %x", record.TraceId, record.SpanId)
+	}
+	if findAttribute(resourceAttributes, "service.name").GetStringValue() != "my-service" {
+		t.Errorf("global labels should be resource attributes %v", resourceAttributes)
+	}
+}
+
+func TestOtlpHandlerOverGrpc(t *testing.T) {
+	receiver := &otlpReceiver{}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	collogspb.RegisterLogsServiceServer(server, receiver)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	initWithOtlpHandler(t, map[string]any{"protocol": "grpc", "endpoint": "http://" + listener.Addr().String()})
+	kt_logging.GetLogger("otlp").Error("over grpc")
+	if err := kt_logging.Sync(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	records, _, _ := receiver.logRecords()
+	if len(records) != 1 || records[0].Body.GetStringValue() != "over grpc" || records[0].SeverityNumber != logspb.SeverityNumber_SEVERITY_NUMBER_ERROR {
+		t.Fatalf("unexpected log records %v", records)
+	}
+}
+
+func TestOtlpHandlerPicksUpGlobalLabelsSetAfterInit(t *testing.T) {
+	receiver := &otlpReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	// the order the README shows: init first then the global labels
+	initWithOtlpHandler(t, map[string]any{"endpoint": server.URL})
+	kt_logging.SetGlobalLabels([]kt_logging.Label{kt_logging.StringLabel("service.name", "late-service")})
+	defer kt_logging.SetGlobalLabels(nil)
+
+	kt_logging.GetLogger("otlp").Info("hello")
+	if err := kt_logging.Sync(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	records, _, resourceAttributes := receiver.logRecords()
+	if len(records) != 1 {
+		t.Fatalf("expected 1 log record but got %v", records)
+	}
+	if findAttribute(resourceAttributes, "service.name").GetStringValue() != "late-service" {
+		t.Errorf("expected the global labels as resource attributes but got %v", resourceAttributes)
+	}
+}
+
+func TestOtlpHandlerDoesNotLoseEventsWhileGlobalLabelsChange(t *testing.T) {
+	receiver := &otlpReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	// the queue of the SDK must not drop anything
+	initWithOtlpHandler(t, map[string]any{"endpoint": server.URL, "maxQueueSize": 10000})
+	defer kt_logging.SetGlobalLabels(nil)
+
+	logger := kt_logging.GetLogger("otlp")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			kt_logging.SetGlobalLabels([]kt_logging.Label{kt_logging.IntLabel("generation", int64(i))})
+			time.Sleep(100 * time.Microsecond)
+		}
+	}()
+	go func() {
+		// syncing waits for the replaced providers - while new ones are being replaced
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				kt_logging.Sync()
+			}
+		}
+	}()
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				logger.Info("event %d", i)
+			}
+		}()
+	}
+	wg.Wait()
+	<-done
+	if err := kt_logging.Sync(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if records, _, _ := receiver.logRecords(); len(records) != 8000 {
+		t.Errorf("expected 8000 log records but got %v", len(records))
+	}
+}
@@ -0,0 +1,93 @@
+//go:build linux
+
+package kt_logging_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+)
+
+func TestRollingFileAppliesFileMode(t *testing.T) {
+	dir := t.TempDir()
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Level:       "debug",
+		RollingFile: &kt_logging.RollingFileModel{File: filepath.Join(dir, "app.log"), FileMode: "0600"},
+	})
+	kt_logging.GetLogger("rolling").Info("secret")
+	if err := kt_logging.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(dir, "app.log"))
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected file mode 0600 but got %v (%v)", info.Mode().Perm(), err)
+	}
+}
+
+func TestRollingFileReopensOnSignalAfterMove(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Level:       "debug",
+		RollingFile: &kt_logging.RollingFileModel{File: path, ReopenOnSignal: true},
+	})
+	defer kt_logging.Shutdown(context.Background())
+	logger := kt_logging.GetLogger("rolling")
+	logger.Info("before move")
+
+	// this is what logrotate does with 'create' style
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatalf("failed to move: %v", err)
+	}
+	syscall.Kill(os.Getpid(), syscall.SIGHUP)
+
+	// the signal is processed in the background
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		logger.Info("after move")
+		content, _ := os.ReadFile(path)
+		if strings.Contains(string(content), "after move") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("file was not reopened")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	moved, _ := os.ReadFile(path + ".1")
+	if !strings.Contains(string(moved), "before move") {
+		t.Errorf("unexpected content in the moved file: %s", moved)
+	}
+}
+
+func TestRollingFileContinuesAfterCopyTruncate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Level:       "debug",
+		RollingFile: &kt_logging.RollingFileModel{File: path, ReopenOnSignal: true},
+	})
+	logger := kt_logging.GetLogger("rolling")
+	logger.Info("before truncate")
+
+	// this is what logrotate does with 'copytruncate' style
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+	logger.Info("after truncate")
+	if err := kt_logging.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+
+	content, _ := os.ReadFile(path)
+	if bytes.IndexByte(content, 0) >= 0 || strings.Contains(string(content), "before truncate") || !strings.Contains(string(content), "after truncate") {
+		t.Errorf("unexpected content after truncate: %q", content)
+	}
+}
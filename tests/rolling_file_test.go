@@ -0,0 +1,284 @@
+package kt_logging_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+)
+
+func listDir(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to list %v: %v", dir, err)
+	}
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRollingFileRotatesByTimeWithFilePattern(t *testing.T) {
+	dir := t.TempDir()
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Level:       "debug",
+		Encoding:    "json",
+		RollingFile: &kt_logging.RollingFileModel{FilePattern: filepath.Join(dir, "logs", "app-%H%M%S.log"), RotateEvery: "1s"},
+	})
+	logger := kt_logging.GetLogger("rolling")
+	logger.Info("first period")
+	// let's step into the next second
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second + 50*time.Millisecond)))
+	logger.Info("second period")
+	if err := kt_logging.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+
+	names := listDir(t, filepath.Join(dir, "logs"))
+	if len(names) != 2 {
+		t.Fatalf("expected 2 files but got %v", names)
+	}
+	for idx, message := range []string{"first period", "second period"} {
+		content, _ := os.ReadFile(filepath.Join(dir, "logs", names[idx]))
+		if !strings.Contains(string(content), message) || strings.Count(string(content), "\n") != 1 {
+			t.Errorf("unexpected content in %v: %s", names[idx], content)
+		}
+	}
+}
+
+func TestRollingFileCombinesTimeAndSizeRotation(t *testing.T) {
+	dir := t.TempDir()
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Level:    "debug",
+		Encoding: "json",
+		RollingFile: &kt_logging.RollingFileModel{
+			File: filepath.Join(dir, "app.log"), RotateEvery: "daily", MaxSizeMb: 1, MaxBackups: 1, Compress: true,
+		},
+	})
+	logger := kt_logging.GetLogger("rolling")
+	payload := strings.Repeat("x", 10*1024)
+	// ~3MB so the file is rotated by size twice
+	for i := int64(0); i < 300; i++ {
+		logger.WithLabel(kt_logging.IntLabel("i", i)).Info(payload)
+	}
+	if err := kt_logging.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+
+	names := listDir(t, dir)
+	if len(names) != 2 || !strings.HasPrefix(names[0], "app-") || !strings.HasSuffix(names[0], ".log.gz") || names[1] != "app.log" {
+		t.Fatalf("expected one compressed backup besides the log file but got %v", names)
+	}
+	info, _ := os.Stat(filepath.Join(dir, "app.log"))
+	if info.Size() > 1024*1024 {
+		t.Errorf("log file should not exceed the max size but it is %d bytes", info.Size())
+	}
+}
+
+func TestRollingFileRejectsInvalidConfig(t *testing.T) {
+	for _, model := range []kt_logging.RollingFileModel{
+		{File: "app.log", RotateEvery: "weekly"},
+		{File: "app.log", FilePattern: "app-%Y.log"},
+		{FilePattern: "app-%Q.log"},
+	} {
+		cfgPath := filepath.Join(t.TempDir(), "log-config.json")
+		writeJsonConfig(t, cfgPath, kt_logging.ConfigModel{
+			Loggers:  map[string]kt_logging.LoggerConfigModel{"root": {Level: "debug", HandlerNames: []string{"handler"}}},
+			Handlers: map[string]kt_logging.HandlerConfigModel{"handler": {RollingFile: &model}},
+		})
+		if err := kt_logging.InitFromConfig(cfgPath); err == nil {
+			t.Errorf("init should fail with %+v", model)
+		}
+	}
+}
+
+func TestRotateFilesInvokesRotationHooks(t *testing.T) {
+	dir := t.TempDir()
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Level:       "debug",
+		Encoding:    "json",
+		RollingFile: &kt_logging.RollingFileModel{File: filepath.Join(dir, "app.log")},
+	})
+	rotated := make(chan [2]string, 1)
+	kt_logging.RegisterRotationHook("test", func(handlerName string, rotatedFile string) {
+		rotated <- [2]string{handlerName, rotatedFile}
+	})
+	defer kt_logging.RegisterRotationHook("test", nil)
+
+	logger := kt_logging.GetLogger("rolling")
+	logger.Info("before rotation")
+	if err := kt_logging.RotateFiles(); err != nil {
+		t.Fatalf("rotation failed: %v", err)
+	}
+	logger.Info("after rotation")
+
+	select {
+	case event := <-rotated:
+		content, _ := os.ReadFile(event[1])
+		if event[0] != "handler" || !strings.Contains(string(content), "before rotation") || strings.Contains(string(content), "after rotation") {
+			t.Errorf("unexpected rotation event %v with content %s", event, content)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("rotation hook was not invoked")
+	}
+	if err := kt_logging.RotateFiles("missing"); err == nil {
+		t.Errorf("rotating a missing handler should fail")
+	}
+	if err := kt_logging.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	content, _ := os.ReadFile(filepath.Join(dir, "app.log"))
+	if !strings.Contains(string(content), "after rotation") || strings.Contains(string(content), "before rotation") {
+		t.Errorf("unexpected content after rotation: %s", content)
+	}
+}
+
+func TestRollingFileKeepsBackupsRotatedWithinTheSameMillisecond(t *testing.T) {
+	dir := t.TempDir()
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Level:       "debug",
+		Encoding:    "json",
+		RollingFile: &kt_logging.RollingFileModel{File: filepath.Join(dir, "app.log")},
+	})
+	logger := kt_logging.GetLogger("rolling")
+	for i := 0; i < 5; i++ {
+		logger.Info("event %d", i)
+		if err := kt_logging.RotateFiles(); err != nil {
+			t.Fatalf("rotation failed: %v", err)
+		}
+	}
+	if err := kt_logging.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+
+	names := listDir(t, dir)
+	content := ""
+	for _, name := range names {
+		fileContent, _ := os.ReadFile(filepath.Join(dir, name))
+		content += string(fileContent)
+	}
+	for i := 0; i < 5; i++ {
+		if !strings.Contains(content, fmt.Sprintf("event %d", i)) {
+			t.Errorf("event %d was lost - files: %v", i, names)
+		}
+	}
+}
+
+func TestRollingFileRotatesFileOfEarlierPeriodWithHooks(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	// left behind by an earlier run two days ago
+	writeFile(t, path, "old\n")
+	os.Chtimes(path, time.Now().AddDate(0, 0, -2), time.Now().AddDate(0, 0, -2))
+	rotated := make(chan string, 1)
+	kt_logging.RegisterRotationHook("test", func(handlerName string, rotatedFile string) {
+		rotated <- rotatedFile
+	})
+	defer kt_logging.RegisterRotationHook("test", nil)
+
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Level:       "debug",
+		Encoding:    "json",
+		RollingFile: &kt_logging.RollingFileModel{File: path, RotateEvery: "daily"},
+	})
+	kt_logging.GetLogger("rolling").Info("new")
+	select {
+	case rotatedFile := <-rotated:
+		if content, _ := os.ReadFile(rotatedFile); string(content) != "old\n" {
+			t.Errorf("unexpected content of the rotated file: %s", content)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("rotation hook was not invoked for the file of the earlier period")
+	}
+}
+
+func TestRollingFileRetentionKeepsFilesNotRotatedByUs(t *testing.T) {
+	for _, model := range []kt_logging.RollingFileModel{
+		{File: "app.log", MaxBackups: 1, MaxAgeDays: 1},
+		{FilePattern: "app-%Y%m%d.log", MaxBackups: 1, MaxAgeDays: 1},
+	} {
+		dir := t.TempDir()
+		// these all match the glob of the backups
+		siblings := []string{"app-audit.log", "app-notes.1.log", "app-2020.log", "app-20201301.log"}
+		for _, name := range siblings {
+			writeFile(t, filepath.Join(dir, name), "not a backup\n")
+			// old enough for maxAgeDays
+			os.Chtimes(filepath.Join(dir, name), time.Now().AddDate(0, 0, -10), time.Now().AddDate(0, 0, -10))
+		}
+		if model.File != "" {
+			model.File = filepath.Join(dir, model.File)
+		} else {
+			model.FilePattern = filepath.Join(dir, model.FilePattern)
+		}
+		initWithSingleHandler(t, kt_logging.HandlerConfigModel{Level: "debug", Encoding: "json", RollingFile: &model})
+
+		logger := kt_logging.GetLogger("rolling")
+		for i := 0; i < 3; i++ {
+			logger.Info("event %d", i)
+			// the names of the backups have millisecond precision
+			time.Sleep(5 * time.Millisecond)
+			if err := kt_logging.RotateFiles(); err != nil {
+				t.Fatalf("rotation failed: %v", err)
+			}
+		}
+		logger.Info("last event")
+		if err := kt_logging.Shutdown(context.Background()); err != nil {
+			t.Fatalf("shutdown failed: %v", err)
+		}
+
+		names := listDir(t, dir)
+		for _, name := range siblings {
+			content, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil || string(content) != "not a backup\n" {
+				t.Errorf("%v must not be touched by the retention of %+v - files: %v", name, model, names)
+			}
+		}
+		// the current file + 1 backup - and the kept backup is the newest
+		if len(names) != len(siblings)+2 {
+			t.Errorf("expected one backup besides the siblings with %+v but got %v", model, names)
+		}
+		found := false
+		for _, name := range names {
+			content, _ := os.ReadFile(filepath.Join(dir, name))
+			found = found || strings.Contains(string(content), "event 2")
+		}
+		if !found {
+			t.Errorf("the newest backup must be kept with %+v - files: %v", model, names)
+		}
+	}
+}
+
+func TestRollingFileTakesOverLumberjackBackups(t *testing.T) {
+	dir := t.TempDir()
+	// left behind by the previous version using lumberjack - and a file of someone else
+	for _, name := range []string{"app-2024-01-01T10-00-00.000.log.gz", "app-2024-01-02T10-00-00.000.log", "app-audit.log"} {
+		writeFile(t, filepath.Join(dir, name), "old\n")
+	}
+	// the config as it was with lumberjack
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Level:       "debug",
+		Encoding:    "json",
+		RollingFile: &kt_logging.RollingFileModel{File: filepath.Join(dir, "app.log"), MaxSizeMb: 1, MaxBackups: 2},
+	})
+	kt_logging.GetLogger("rolling").Info("before rotation")
+	if err := kt_logging.RotateFiles(); err != nil {
+		t.Fatalf("rotation failed: %v", err)
+	}
+	if err := kt_logging.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+
+	names := listDir(t, dir)
+	// the oldest backup is over maxBackups - the rest stays
+	if len(names) != 4 || names[0] != "app-2024-01-02T10-00-00.000.log" || names[2] != "app-audit.log" || names[3] != "app.log" {
+		t.Errorf("unexpected files after rotation: %v", names)
+	}
+}
@@ -0,0 +1,57 @@
+package kt_logging_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+)
+
+func TestSamplingDropsFloodsAndSummarizes(t *testing.T) {
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{
+		Type:     "memory",
+		Level:    "debug",
+		Sampling: &kt_logging.SamplingModel{Initial: 2, Thereafter: 5, Tick: "200ms"},
+	})
+	logger := kt_logging.GetLogger("hot.loop")
+	for i := 1; i <= 12; i++ {
+		// the params do not matter - the template is the key
+		logger.Info("processing item %d", i)
+	}
+	logger.Warn("processing item %d", 99)
+	logger.Info("something else")
+
+	records, _ := kt_logging.RecentEvents("handler", kt_logging.RecentEventsFilter{})
+	// 1, 2 are the initial ones then every 5th: 7, 12
+	if got := strings.Join(messagesOf(records), ","); got != "processing item 1,processing item 2,processing item 7,processing item 12,processing item 99,something else" {
+		t.Fatalf("unexpected sampled events: %v", got)
+	}
+
+	// the summary comes at the end of the tick
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		records, _ = kt_logging.RecentEvents("handler", kt_logging.RecentEventsFilter{Contains: "sampled out"})
+		if len(records) > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected 1 summary event but got %v", messagesOf(records))
+	}
+	summary := records[0]
+	if summary.Level != kt_logging.InfoLevel || summary.LoggerName != "hot.loop" || !strings.HasPrefix(summary.Message, "8 log events were sampled out") {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	if summary.Labels[0].GetStringValue() != "processing item %d" || summary.Labels[1].GetIntValue() != 8 {
+		t.Errorf("unexpected summary labels %v", summary.Labels)
+	}
+
+	// new tick - the initial ones go through again
+	logger.Info("processing item %d", 13)
+	records, _ = kt_logging.RecentEvents("handler", kt_logging.RecentEventsFilter{Limit: 1})
+	if records[0].Message != "processing item 13" {
+		t.Errorf("expected the event to pass in the new tick but got %v", records[0].Message)
+	}
+}
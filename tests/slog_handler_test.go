@@ -0,0 +1,92 @@
+package kt_logging_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+)
+
+func TestSlogHandlerRoutesRecordsIntoLogger(t *testing.T) {
+	logPath := initWithJsonFileOutput(t)
+	kt_logging.SetGlobalLabels([]kt_logging.Label{kt_logging.StringLabel("appName", "test")})
+	defer kt_logging.SetGlobalLabels([]kt_logging.Label{})
+
+	logger := slog.New(kt_logging.NewSlogHandler("thirdparty"))
+	logger.With("component", "db").
+		WithGroup("req").
+		Warn("100% done", "id", 5, slog.Group("user", "admin", true), "ratio", 0.5)
+
+	events := readJsonLogEvents(t, logPath)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %v", events)
+	}
+	event := events[0]
+	expected := map[string]any{
+		"level":          "warn",
+		"message":        "100% done",
+		"logger":         "thirdparty",
+		"appName":        "test",
+		"component":      "db",
+		"req.id":         float64(5),
+		"req.user.admin": true,
+		"req.ratio":      0.5,
+	}
+	for key, value := range expected {
+		if event[key] != value {
+			t.Errorf("expected %v=%v but got %v in event %v", key, value, event[key], event)
+		}
+	}
+}
+
+func TestSlogHandlerEnabledFollowsLoggerLevel(t *testing.T) {
+	initWithJsonFileOutput(t)
+	kt_logging.SetLevel("thirdparty", kt_logging.WarningLevel)
+
+	handler := kt_logging.NewSlogHandler("thirdparty")
+	if handler.Enabled(context.Background(), slog.LevelInfo) {
+		t.Errorf("info should not be enabled on a warning level logger")
+	}
+	if !handler.Enabled(context.Background(), slog.LevelError) {
+		t.Errorf("error should be enabled on a warning level logger")
+	}
+}
+
+func TestOnlySlogAndWriterEventsUseTheMessageAsTemplate(t *testing.T) {
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{Type: "memory", Level: "debug"})
+	logger := kt_logging.GetLogger("app")
+	logger.Info("%s", "user input")
+	slog.New(kt_logging.NewSlogHandler("app")).Info("from slog")
+	logger.Writer(kt_logging.InfoLevel).Write([]byte("from writer\n"))
+
+	records, err := kt_logging.RecentEvents("handler", kt_logging.RecentEventsFilter{})
+	if err != nil {
+		t.Fatalf("failed to get recent events: %v", err)
+	}
+	templates := []string{}
+	for _, record := range records {
+		templates = append(templates, record.MessageTemplate)
+	}
+	if len(templates) != 3 || templates[0] != "%s" || templates[1] != "from slog" || templates[2] != "from writer" {
+		t.Errorf("unexpected message templates: %q", templates)
+	}
+}
+
+func TestSlogHandlerKeepsTheTimeOfTheRecord(t *testing.T) {
+	initWithSingleHandler(t, kt_logging.HandlerConfigModel{Type: "memory", Level: "debug"})
+	eventTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	record := slog.NewRecord(eventTime, slog.LevelInfo, "from the past", 0)
+	if err := kt_logging.NewSlogHandler("app").Handle(context.Background(), record); err != nil {
+		t.Fatalf("handle failed: %v", err)
+	}
+
+	records, err := kt_logging.RecentEvents("handler", kt_logging.RecentEventsFilter{})
+	if err != nil {
+		t.Fatalf("failed to get recent events: %v", err)
+	}
+	if len(records) != 1 || !records[0].Time.Equal(eventTime) {
+		t.Errorf("expected the time of the slog record but got %v", records)
+	}
+}
@@ -0,0 +1,67 @@
+package kt_logging_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/keytiles/lib-logging-golang/v2/pkg/kt_logging"
+)
+
+// initializes the logging with a "root" logger on debug level writing JSON into a file - returns the path of the file
+func initWithJsonFileOutput(t *testing.T) string {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "log.jsonl")
+	cfgPath := filepath.Join(dir, "log-config.json")
+	config := kt_logging.ConfigModel{
+		Loggers: map[string]kt_logging.LoggerConfigModel{
+			"root": {Name: "root", Level: "debug", HandlerNames: []string{"file_json"}},
+		},
+		Handlers: map[string]kt_logging.HandlerConfigModel{
+			"file_json": {Level: "trace", Encoding: "json", OutputPaths: []string{logPath}},
+		},
+	}
+	writeJsonConfig(t, cfgPath, config)
+	if err := kt_logging.InitFromConfig(cfgPath); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	return logPath
+}
+
+func writeJsonConfig(t *testing.T, cfgPath string, config kt_logging.ConfigModel) {
+	content, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("failed to marshal config: %v", err)
+	}
+	if err := os.WriteFile(cfgPath, content, 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+}
+
+// reads back the JSON log events from the given file
+func readJsonLogEvents(t *testing.T, logPath string) []map[string]any {
+	file, err := os.Open(logPath)
+	if err != nil {
+		t.Fatalf("failed to open log file: %v", err)
+	}
+	defer file.Close()
+
+	events := []map[string]any{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		event := map[string]any{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("invalid JSON log line '%v': %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+}